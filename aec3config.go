@@ -0,0 +1,193 @@
+package apm
+
+/*
+#include <bridge.h>
+*/
+import "C"
+
+import "fmt"
+
+// Aec3DelayConfig tunes AEC3's delay estimator, mirroring
+// EchoCanceller3Config::Delay.
+type Aec3DelayConfig struct {
+	DefaultDelayMs           int     `json:"default_delay_ms"`
+	DownSamplingFactor       int     `json:"down_sampling_factor"`
+	NumFilters               int     `json:"num_filters"`
+	HysteresisLimitBlocks    int     `json:"hysteresis_limit_blocks"`
+	FixedCaptureDelaySamples int     `json:"fixed_capture_delay_samples"`
+	DelayEstimateSmoothing   float32 `json:"delay_estimate_smoothing"`
+}
+
+// Aec3FilterConfig tunes AEC3's adaptive filter, mirroring a condensed view
+// of EchoCanceller3Config::Filter (the main/shadow sub-configs are collapsed
+// to their most commonly retuned fields).
+type Aec3FilterConfig struct {
+	MainLengthBlocks   int     `json:"main_length_blocks"`
+	ShadowLengthBlocks int     `json:"shadow_length_blocks"`
+	LeakageConverged   float32 `json:"leakage_converged"`
+	LeakageDiverged    float32 `json:"leakage_diverged"`
+	ErrorFloor         float32 `json:"error_floor"`
+	ErrorCeil          float32 `json:"error_ceil"`
+	NoiseGate          float32 `json:"noise_gate"`
+}
+
+// Aec3ErleConfig tunes AEC3's echo return loss enhancement estimator,
+// mirroring EchoCanceller3Config::Erle.
+type Aec3ErleConfig struct {
+	Min            float32 `json:"min"`
+	MaxL           float32 `json:"max_l"`
+	MaxH           float32 `json:"max_h"`
+	OnsetDetection bool    `json:"onset_detection"`
+}
+
+// Aec3SuppressorTuning is one of the nearend/normal/high operating-point
+// tunings in Aec3SuppressorConfig, mirroring
+// EchoCanceller3Config::Suppressor::MaskingThresholds.
+type Aec3SuppressorTuning struct {
+	MaskLfEnrTransparent float32 `json:"mask_lf_enr_transparent"`
+	MaskLfEnrSuppress    float32 `json:"mask_lf_enr_suppress"`
+	MaskHfEnrTransparent float32 `json:"mask_hf_enr_transparent"`
+	MaskHfEnrSuppress    float32 `json:"mask_hf_enr_suppress"`
+}
+
+// Aec3SuppressorConfig tunes AEC3's echo suppressor, mirroring
+// EchoCanceller3Config::Suppressor.
+type Aec3SuppressorConfig struct {
+	Nearend                  Aec3SuppressorTuning `json:"nearend"`
+	Normal                   Aec3SuppressorTuning `json:"normal"`
+	High                     Aec3SuppressorTuning `json:"high"`
+	DominantNearendDetection bool                 `json:"dominant_nearend_detection"`
+}
+
+// EchoCanceller3Config is the detailed AEC3 tuning knob set, mirroring
+// api/audio/echo_canceller3_config.h. It is applied via
+// Handle.ApplyAec3Config (or EchoCancellationConfig.Aec3 at Create time)
+// instead of through the coarse Enabled/MobileMode/StreamDelayMs fields on
+// EchoCancellationConfig, and is JSON-marshalable so a tuning can be shipped
+// as a per-device config file the way the WebRTC team distributes AEC3
+// tunings.
+type EchoCanceller3Config struct {
+	Delay      Aec3DelayConfig  `json:"delay"`
+	Filter     Aec3FilterConfig `json:"filter"`
+	Erle       Aec3ErleConfig   `json:"erle"`
+	EpStrength float32          `json:"ep_strength"`
+	// EchoModelEnabled selects AEC3's echo path model-based suppression in
+	// addition to the adaptive filter, trading CPU for better suppression
+	// of nonlinear echo paths.
+	EchoModelEnabled bool                 `json:"echo_model_enabled"`
+	Suppressor       Aec3SuppressorConfig `json:"suppressor"`
+}
+
+// DefaultAec3Config returns AEC3's built-in default tuning, suitable as a
+// starting point for a device-specific override.
+func DefaultAec3Config() EchoCanceller3Config {
+	return EchoCanceller3Config{
+		Delay: Aec3DelayConfig{
+			DefaultDelayMs:           0,
+			DownSamplingFactor:       4,
+			NumFilters:               5,
+			HysteresisLimitBlocks:    1,
+			FixedCaptureDelaySamples: 0,
+			DelayEstimateSmoothing:   0.7,
+		},
+		Filter: Aec3FilterConfig{
+			MainLengthBlocks:   13,
+			ShadowLengthBlocks: 13,
+			LeakageConverged:   0.003,
+			LeakageDiverged:    0.05,
+			ErrorFloor:         0.001,
+			ErrorCeil:          2.0,
+			NoiseGate:          20075.0,
+		},
+		Erle: Aec3ErleConfig{
+			Min:            1.0,
+			MaxL:           4.0,
+			MaxH:           1.5,
+			OnsetDetection: true,
+		},
+		EpStrength:       1.0,
+		EchoModelEnabled: true,
+		Suppressor: Aec3SuppressorConfig{
+			Nearend: Aec3SuppressorTuning{
+				MaskLfEnrTransparent: 0.3,
+				MaskLfEnrSuppress:    1.25,
+				MaskHfEnrTransparent: 0.07,
+				MaskHfEnrSuppress:    0.5,
+			},
+			Normal: Aec3SuppressorTuning{
+				MaskLfEnrTransparent: 0.4,
+				MaskLfEnrSuppress:    1.0,
+				MaskHfEnrTransparent: 0.14,
+				MaskHfEnrSuppress:    0.3,
+			},
+			High: Aec3SuppressorTuning{
+				MaskLfEnrTransparent: 0.6,
+				MaskLfEnrSuppress:    1.0,
+				MaskHfEnrTransparent: 0.3,
+				MaskHfEnrSuppress:    0.3,
+			},
+			DominantNearendDetection: true,
+		},
+	}
+}
+
+// ApplyAec3Config constructs a webrtc::EchoCanceller3 with cfg's tuning and
+// installs it on h via an EchoControlFactory, replacing whatever echo
+// canceler h was created with. It only takes effect when
+// EchoCancellationConfig.MobileMode is not set; AECM has no AEC3 tuning to
+// apply.
+func (h *Handle) ApplyAec3Config(cfg EchoCanceller3Config) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+
+	cCfg := C.ApmAec3Config{
+		delay: C.ApmAec3Delay{
+			default_delay_ms:            C.int(cfg.Delay.DefaultDelayMs),
+			down_sampling_factor:        C.int(cfg.Delay.DownSamplingFactor),
+			num_filters:                 C.int(cfg.Delay.NumFilters),
+			hysteresis_limit_blocks:     C.int(cfg.Delay.HysteresisLimitBlocks),
+			fixed_capture_delay_samples: C.int(cfg.Delay.FixedCaptureDelaySamples),
+			delay_estimate_smoothing:    C.float(cfg.Delay.DelayEstimateSmoothing),
+		},
+		filter: C.ApmAec3Filter{
+			main_length_blocks:   C.int(cfg.Filter.MainLengthBlocks),
+			shadow_length_blocks: C.int(cfg.Filter.ShadowLengthBlocks),
+			leakage_converged:    C.float(cfg.Filter.LeakageConverged),
+			leakage_diverged:     C.float(cfg.Filter.LeakageDiverged),
+			error_floor:          C.float(cfg.Filter.ErrorFloor),
+			error_ceil:           C.float(cfg.Filter.ErrorCeil),
+			noise_gate:           C.float(cfg.Filter.NoiseGate),
+		},
+		erle: C.ApmAec3Erle{
+			min:             C.float(cfg.Erle.Min),
+			max_l:           C.float(cfg.Erle.MaxL),
+			max_h:           C.float(cfg.Erle.MaxH),
+			onset_detection: C.bool(cfg.Erle.OnsetDetection),
+		},
+		ep_strength:        C.float(cfg.EpStrength),
+		echo_model_enabled: C.bool(cfg.EchoModelEnabled),
+		suppressor: C.ApmAec3Suppressor{
+			nearend:                    aec3SuppressorTuningToC(cfg.Suppressor.Nearend),
+			normal:                     aec3SuppressorTuningToC(cfg.Suppressor.Normal),
+			high:                       aec3SuppressorTuningToC(cfg.Suppressor.High),
+			dominant_nearend_detection: C.bool(cfg.Suppressor.DominantNearendDetection),
+		},
+	}
+
+	result := C.ApplyAec3Config(h.ptr, cCfg)
+	if C.is_success(result) == 0 {
+		return fmt.Errorf("failed to apply AEC3 config: error code %d", int(result))
+	}
+
+	return nil
+}
+
+func aec3SuppressorTuningToC(t Aec3SuppressorTuning) C.ApmAec3SuppressorTuning {
+	return C.ApmAec3SuppressorTuning{
+		mask_lf_enr_transparent: C.float(t.MaskLfEnrTransparent),
+		mask_lf_enr_suppress:    C.float(t.MaskLfEnrSuppress),
+		mask_hf_enr_transparent: C.float(t.MaskHfEnrTransparent),
+		mask_hf_enr_suppress:    C.float(t.MaskHfEnrSuppress),
+	}
+}
@@ -0,0 +1,42 @@
+package apm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDefaultAec3ConfigValues(t *testing.T) {
+	cfg := DefaultAec3Config()
+
+	if cfg.Delay.NumFilters != 5 {
+		t.Errorf("Delay.NumFilters = %d, want 5", cfg.Delay.NumFilters)
+	}
+	if cfg.Filter.MainLengthBlocks != 13 {
+		t.Errorf("Filter.MainLengthBlocks = %d, want 13", cfg.Filter.MainLengthBlocks)
+	}
+	if !cfg.EchoModelEnabled {
+		t.Error("EchoModelEnabled = false, want true")
+	}
+	if !cfg.Suppressor.DominantNearendDetection {
+		t.Error("Suppressor.DominantNearendDetection = false, want true")
+	}
+}
+
+func TestEchoCanceller3ConfigJSONRoundTrip(t *testing.T) {
+	want := DefaultAec3Config()
+	want.EpStrength = 0.5
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got EchoCanceller3Config
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("round-tripped config = %+v, want %+v", got, want)
+	}
+}
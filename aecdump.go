@@ -0,0 +1,216 @@
+package apm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// aecDumpEventType identifies the kind of frame written to an AEC dump,
+// mirroring the subset of webrtc.audioproc.Event.Type values this package
+// understands (INIT, STREAM, REVERSE_STREAM).
+type aecDumpEventType uint8
+
+const (
+	aecDumpEventInit aecDumpEventType = iota + 1
+	aecDumpEventStream
+	aecDumpEventReverseStream
+)
+
+// aecDumpWriter appends length-delimited event frames to an underlying file.
+// It does not attempt to byte-for-byte match the upstream protobuf wire
+// format; it is a minimal hand-rolled encoder used internally to build the
+// fixtures ReplayAecDumpStream replays. For a dump that is actually
+// compatible with WebRTC's audioproc_f tool, use Handle.AttachAecDump/
+// StartAecDump instead.
+type aecDumpWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newAecDumpWriter(path string) (*aecDumpWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aec dump file: %w", err)
+	}
+	return &aecDumpWriter{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (d *aecDumpWriter) writeFrame(eventType aecDumpEventType, payload []byte) error {
+	header := make([]byte, 5)
+	header[0] = byte(eventType)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+
+	if _, err := d.w.Write(header); err != nil {
+		return err
+	}
+	_, err := d.w.Write(payload)
+	return err
+}
+
+func (d *aecDumpWriter) writeInit(sampleRateHz, numChannels, numReverseChannels int) error {
+	payload := make([]byte, 12)
+	binary.BigEndian.PutUint32(payload[0:], uint32(sampleRateHz))
+	binary.BigEndian.PutUint32(payload[4:], uint32(numChannels))
+	binary.BigEndian.PutUint32(payload[8:], uint32(numReverseChannels))
+	return d.writeFrame(aecDumpEventInit, payload)
+}
+
+func (d *aecDumpWriter) writeStream(numChannels int, input, output []float32, delayMs int, keyPressed bool) error {
+	payload := make([]byte, 0, 13+4*(len(input)+len(output)))
+	payload = appendUint32(payload, uint32(numChannels))
+	payload = appendUint32(payload, uint32(delayMs))
+	payload = appendBool(payload, keyPressed)
+	payload = appendFloat32Slice(payload, input)
+	payload = appendFloat32Slice(payload, output)
+	return d.writeFrame(aecDumpEventStream, payload)
+}
+
+func (d *aecDumpWriter) writeReverseStream(numChannels int, samples []float32) error {
+	payload := make([]byte, 0, 4+4*len(samples))
+	payload = appendUint32(payload, uint32(numChannels))
+	payload = appendFloat32Slice(payload, samples)
+	return d.writeFrame(aecDumpEventReverseStream, payload)
+}
+
+func (d *aecDumpWriter) Close() error {
+	if err := d.w.Flush(); err != nil {
+		d.f.Close()
+		return err
+	}
+	return d.f.Close()
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func appendFloat32Slice(b []byte, samples []float32) []byte {
+	b = appendUint32(b, uint32(len(samples)))
+	for _, s := range samples {
+		b = appendUint32(b, math.Float32bits(s))
+	}
+	return b
+}
+
+// ReplayAecDumpStream re-runs path's capture/render frames through a fresh
+// Processor built with cfg, streaming a Stats snapshot after every
+// processed capture frame instead of buffering the whole file and
+// returning one summary at the end. It's the companion to
+// Handle.StartAecDump/StopAecDump for watching a long replay's progress, or
+// comparing Stats across configs frame-by-frame while A/B tuning.
+//
+// The returned channel is closed when the dump is exhausted or a read or
+// process error is hit; a malformed or truncated dump therefore just ends
+// the stream early instead of surfacing through the channel itself.
+func ReplayAecDumpStream(path string, cfg Config) (<-chan Stats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aec dump: %w", err)
+	}
+
+	processor, err := New(cfg)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	out := make(chan Stats)
+
+	go func() {
+		defer f.Close()
+		defer processor.Close()
+		defer close(out)
+		// The decoders index the payload without bounds checks; recovering
+		// from a short payload ends the replay the same way a read error does.
+		defer func() { recover() }()
+
+		r := bufio.NewReader(f)
+		for {
+			eventType, payload, err := readFrame(r)
+			if err != nil {
+				return
+			}
+
+			switch aecDumpEventType(eventType) {
+			case aecDumpEventReverseStream:
+				_, samples := decodeReverseStream(payload)
+				if err := processor.ProcessRender(samples); err != nil {
+					return
+				}
+
+			case aecDumpEventStream:
+				_, delayMs, _, input, _ := decodeStream(payload)
+				if err := processor.SetStreamDelay(delayMs); err != nil {
+					return
+				}
+				if _, err := processor.ProcessCapture(input); err != nil {
+					return
+				}
+				out <- processor.GetStats()
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func readFrame(r *bufio.Reader) (eventType uint8, payload []byte, err error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	eventType = header[0]
+	length := binary.BigEndian.Uint32(header[1:])
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return eventType, payload, nil
+}
+
+func decodeReverseStream(payload []byte) (numChannels int, samples []float32) {
+	numChannels = int(binary.BigEndian.Uint32(payload[0:]))
+	samples = decodeFloat32Slice(payload[4:])
+	return numChannels, samples
+}
+
+func decodeStream(payload []byte) (numChannels, delayMs int, keyPressed bool, input, output []float32) {
+	numChannels = int(binary.BigEndian.Uint32(payload[0:]))
+	delayMs = int(binary.BigEndian.Uint32(payload[4:]))
+	keyPressed = payload[8] != 0
+
+	rest := payload[9:]
+	inputLen := int(binary.BigEndian.Uint32(rest[0:]))
+	rest = rest[4:]
+	input = decodeFloat32SliceN(rest, inputLen)
+	rest = rest[4*inputLen:]
+	output = decodeFloat32Slice(rest)
+	return numChannels, delayMs, keyPressed, input, output
+}
+
+func decodeFloat32Slice(b []byte) []float32 {
+	n := int(binary.BigEndian.Uint32(b[0:]))
+	return decodeFloat32SliceN(b[4:], n)
+}
+
+func decodeFloat32SliceN(b []byte, n int) []float32 {
+	out := make([]float32, n)
+	for i := 0; i < n; i++ {
+		out[i] = math.Float32frombits(binary.BigEndian.Uint32(b[i*4:]))
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package apm
+
+import (
+	"bufio"
+	"os"
+	"testing"
+)
+
+func TestAecDumpRoundtrip(t *testing.T) {
+	path := t.TempDir() + "/dump.bin"
+
+	w, err := newAecDumpWriter(path)
+	if err != nil {
+		t.Fatalf("newAecDumpWriter failed: %v", err)
+	}
+
+	if err := w.writeInit(SampleRateHz, 1, 1); err != nil {
+		t.Fatalf("writeInit failed: %v", err)
+	}
+
+	render := generateSineWave(1000, 0.4, NumSamplesPerFrame)
+	if err := w.writeReverseStream(1, render); err != nil {
+		t.Fatalf("writeReverseStream failed: %v", err)
+	}
+
+	capture := generateSineWave(500, 0.3, NumSamplesPerFrame)
+	output := make([]float32, len(capture))
+	copy(output, capture)
+	if err := w.writeStream(1, capture, output, 20, false); err != nil {
+		t.Fatalf("writeStream failed: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen dump: %v", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	eventType, payload, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame (init) failed: %v", err)
+	}
+	if aecDumpEventType(eventType) != aecDumpEventInit {
+		t.Fatalf("expected init event, got %d", eventType)
+	}
+
+	eventType, payload, err = readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame (reverse stream) failed: %v", err)
+	}
+	if aecDumpEventType(eventType) != aecDumpEventReverseStream {
+		t.Fatalf("expected reverse stream event, got %d", eventType)
+	}
+	_, samples := decodeReverseStream(payload)
+	if len(samples) != len(render) {
+		t.Fatalf("decoded %d render samples, want %d", len(samples), len(render))
+	}
+
+	eventType, payload, err = readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame (stream) failed: %v", err)
+	}
+	if aecDumpEventType(eventType) != aecDumpEventStream {
+		t.Fatalf("expected stream event, got %d", eventType)
+	}
+	numChannels, delayMs, keyPressed, input, decodedOutput := decodeStream(payload)
+	if numChannels != 1 {
+		t.Errorf("numChannels = %d, want 1", numChannels)
+	}
+	if delayMs != 20 {
+		t.Errorf("delayMs = %d, want 20", delayMs)
+	}
+	if keyPressed {
+		t.Errorf("keyPressed = true, want false")
+	}
+	if len(input) != len(capture) || len(decodedOutput) != len(output) {
+		t.Fatalf("decoded stream lengths don't match recorded frame")
+	}
+
+	if _, _, err := readFrame(bufio.NewReader(f)); err == nil {
+		t.Fatal("expected EOF after last recorded frame")
+	}
+}
+
+func TestReplayAecDumpStream(t *testing.T) {
+	path := t.TempDir() + "/dump.bin"
+
+	w, err := newAecDumpWriter(path)
+	if err != nil {
+		t.Fatalf("newAecDumpWriter failed: %v", err)
+	}
+	if err := w.writeInit(SampleRateHz, 1, 1); err != nil {
+		t.Fatalf("writeInit failed: %v", err)
+	}
+
+	const numFrames = 3
+	for i := 0; i < numFrames; i++ {
+		render := generateSineWave(1000, 0.4, NumSamplesPerFrame)
+		if err := w.writeReverseStream(1, render); err != nil {
+			t.Fatalf("writeReverseStream failed: %v", err)
+		}
+		capture := generateSineWave(500, 0.3, NumSamplesPerFrame)
+		if err := w.writeStream(1, capture, capture, 20, false); err != nil {
+			t.Fatalf("writeStream failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	stats, err := ReplayAecDumpStream(path, Config{CaptureChannels: 1, RenderChannels: 1})
+	if err != nil {
+		t.Fatalf("ReplayAecDumpStream failed: %v", err)
+	}
+
+	got := 0
+	for range stats {
+		got++
+	}
+	if got != numFrames {
+		t.Fatalf("received %d stats snapshots, want %d", got, numFrames)
+	}
+}
@@ -0,0 +1,174 @@
+// Package agcloop closes the analog mic gain loop that apm.Handle leaves to
+// the caller: SetStreamAnalogLevel/RecommendedStreamAnalogLevel report where
+// APM's AGC thinks the level should be, but somebody still has to rate-limit
+// those recommendations and push them out to the OS mixer. AnalogGainController
+// is that somebody, modeled on the startup ramp, hysteresis and rate-limiting
+// behavior of Chromium's webrtc::AgcManagerDirect so callers get the same
+// auto-mic-gain UX without reimplementing its control loop in Go.
+package agcloop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CoyAce/apm"
+)
+
+// SetMicVolumeFunc applies level (in the controller's [MinLevel, MaxLevel]
+// range) to the OS mixer, e.g. an ALSA/CoreAudio/WASAPI mic volume control.
+type SetMicVolumeFunc func(level int) error
+
+// Options configures an AnalogGainController.
+type Options struct {
+	Handle *apm.Handle
+
+	// MinLevel/MaxLevel bound the levels ever passed to SetMicVolume and
+	// fed back to Handle.SetStreamAnalogLevel.
+	MinLevel int
+	MaxLevel int
+
+	// InitialLevel is applied immediately by New, before any frames have
+	// been processed, mirroring AgcManagerDirect's own startup level.
+	InitialLevel int
+
+	// SetMicVolume is called with the controller's chosen level whenever it
+	// decides to move the mic volume. It must not block for long: Process
+	// is meant to be called once per capture frame.
+	SetMicVolume SetMicVolumeFunc
+
+	// MinDelta is the hysteresis band: a recommendation within MinDelta of
+	// the current level is treated as noise and ignored.
+	MinDelta int
+
+	// MaxStepsPerSecond rate-limits how often Process is allowed to move
+	// the level, so a flapping recommendation can't chatter the mixer.
+	// Zero means unlimited.
+	MaxStepsPerSecond float64
+
+	// WaitUntilSpeech delays the first adjustment until Process observes a
+	// frame whose speech probability is at least SpeechThreshold, matching
+	// AgcManagerDirect's refusal to adjust gain against silence/noise
+	// before it has ever heard the near-end speaker.
+	WaitUntilSpeech bool
+	SpeechThreshold float64 // default 0.5 when WaitUntilSpeech is set and this is zero
+
+	// CompressionGainDb is the digital compression gain ceiling the
+	// underlying AGC's digital compressor is configured with. It is
+	// reported as-is by CompressionGainDb; this controller does not adjust
+	// it, since it governs a gain stage separate from the analog level
+	// AnalogGainController closes the loop on.
+	CompressionGainDb float32
+}
+
+// AnalogGainController closes the analog mic gain loop: on each capture
+// frame, Process reads Handle's recommended level, clamps/rate-limits/
+// debounces it, and hands the result to SetMicVolume.
+type AnalogGainController struct {
+	handle *apm.Handle
+	opts   Options
+
+	level      int
+	lastStepAt time.Time
+	sawSpeech  bool
+}
+
+// New creates an AnalogGainController and applies opts.InitialLevel as the
+// starting point for both the OS mixer (via opts.SetMicVolume) and Handle's
+// own analog level state.
+func New(opts Options) (*AnalogGainController, error) {
+	if opts.Handle == nil {
+		return nil, fmt.Errorf("agcloop: Handle is required")
+	}
+	if opts.SetMicVolume == nil {
+		return nil, fmt.Errorf("agcloop: SetMicVolume is required")
+	}
+	if opts.MaxLevel <= opts.MinLevel {
+		return nil, fmt.Errorf("agcloop: MaxLevel (%d) must be greater than MinLevel (%d)", opts.MaxLevel, opts.MinLevel)
+	}
+
+	level := clamp(opts.InitialLevel, opts.MinLevel, opts.MaxLevel)
+
+	c := &AnalogGainController{
+		handle: opts.Handle,
+		opts:   opts,
+		level:  level,
+	}
+
+	if err := opts.SetMicVolume(level); err != nil {
+		return nil, fmt.Errorf("agcloop: setting initial mic volume: %w", err)
+	}
+	c.handle.SetStreamAnalogLevel(level)
+
+	return c, nil
+}
+
+// Process should be called once per processed capture frame. It reads
+// Handle.RecommendedStreamAnalogLevel, and, subject to the startup ramp,
+// hysteresis and rate limit configured in Options, applies the new level
+// via SetMicVolume and reports it back to Handle via SetStreamAnalogLevel.
+func (c *AnalogGainController) Process() error {
+	if c.opts.WaitUntilSpeech && !c.sawSpeech {
+		threshold := c.opts.SpeechThreshold
+		if threshold == 0 {
+			threshold = 0.5
+		}
+		if c.handle.GetStats().SpeechProbability < threshold {
+			return nil
+		}
+		c.sawSpeech = true
+	}
+
+	recommended := clamp(c.handle.RecommendedStreamAnalogLevel(), c.opts.MinLevel, c.opts.MaxLevel)
+
+	delta := recommended - c.level
+	if delta == 0 {
+		return nil
+	}
+	if abs(delta) < c.opts.MinDelta {
+		return nil
+	}
+	if c.opts.MaxStepsPerSecond > 0 && !c.lastStepAt.IsZero() {
+		minInterval := time.Duration(float64(time.Second) / c.opts.MaxStepsPerSecond)
+		if time.Since(c.lastStepAt) < minInterval {
+			return nil
+		}
+	}
+
+	if err := c.opts.SetMicVolume(recommended); err != nil {
+		return fmt.Errorf("agcloop: setting mic volume: %w", err)
+	}
+
+	c.level = recommended
+	c.lastStepAt = time.Now()
+	c.handle.SetStreamAnalogLevel(recommended)
+
+	return nil
+}
+
+// Level returns the level last applied via SetMicVolume.
+func (c *AnalogGainController) Level() int {
+	return c.level
+}
+
+// CompressionGainDb returns the digital compression gain ceiling configured
+// in Options, for callers that want to surface both gain stages together.
+func (c *AnalogGainController) CompressionGainDb() float32 {
+	return c.opts.CompressionGainDb
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -37,10 +37,23 @@ type Processor struct {
 	config      Config
 	mu          sync.Mutex
 	numChannels int
+
+	captureResampleIn  *resampler
+	captureResampleOut *resampler
+	capturePending     []float32 // buffered native-rate samples awaiting a full frame
+
+	renderResampleIn *resampler
+	renderPending    []float32
 }
 
 // New creates a new audio processor with the given configuration
 func New(config Config) (*Processor, error) {
+	if err := validateStreamFormat(config.CaptureFormat, config.CaptureChannels); err != nil {
+		return nil, fmt.Errorf("invalid capture format: %w", err)
+	}
+	if err := validateStreamFormat(config.RenderFormat, config.RenderChannels); err != nil {
+		return nil, fmt.Errorf("invalid render format: %w", err)
+	}
 
 	handle, err := Create(config)
 	if err != nil {
@@ -53,6 +66,9 @@ func New(config Config) (*Processor, error) {
 		numChannels: config.CaptureChannels,
 	}
 
+	p.captureResampleIn, p.captureResampleOut = newResamplerPair(config.CaptureFormat, config.CaptureChannels)
+	p.renderResampleIn, _ = newResamplerPair(config.RenderFormat, config.RenderChannels)
+
 	return p, nil
 }
 
@@ -96,6 +112,32 @@ func (p *Processor) ProcessCapture(samples []float32) ([]float32, error) {
 	return output, nil
 }
 
+// ProcessCaptureBeamformed processes one microphone-array capture frame
+// through the beamformer installed by Config.Beamforming, collapsing
+// len(Config.Beamforming.MicGeometry) input channels down to a single
+// spatially-filtered channel before AEC/NS. Requires
+// Config.Beamforming.Enabled.
+func (p *Processor) ProcessCaptureBeamformed(samples []float32) ([]float32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+	if !p.config.Beamforming.Enabled {
+		return nil, fmt.Errorf("beamforming is not enabled")
+	}
+
+	numChannels := len(p.config.Beamforming.MicGeometry)
+	expectedLen := numChannels * NumSamplesPerFrame
+	if len(samples) != expectedLen {
+		return nil, fmt.Errorf("expected %d samples (%d mics x %d samples/frame), got %d",
+			expectedLen, numChannels, NumSamplesPerFrame, len(samples))
+	}
+
+	return p.handle.ProcessCaptureFrameBeamformed(samples, numChannels)
+}
+
 // ProcessCaptureInt16 processes microphone input with int16 samples
 // This is a convenience method that converts from/to int16 format
 func (p *Processor) ProcessCaptureInt16(samples []int16) ([]int16, error) {
@@ -146,7 +188,11 @@ func (p *Processor) ProcessRender(samples []float32) error {
 	renderSamples := make([]float32, len(samples))
 	copy(renderSamples, samples)
 
-	return p.handle.ProcessRenderFrame(renderSamples, p.numChannels)
+	if err := p.handle.ProcessRenderFrame(renderSamples, p.numChannels); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // ProcessRenderInt16 provides speaker output with int16 samples
@@ -171,6 +217,7 @@ func (p *Processor) SetStreamDelay(delayMs int) error {
 	}
 
 	p.handle.SetStreamDelayMs(delayMs)
+
 	return nil
 }
 
@@ -198,6 +245,30 @@ func (p *Processor) GetStats() Stats {
 	return cgoStats
 }
 
+// LastVoiceProbability returns VoiceDetection's speech-activity probability
+// for the last processed capture frame. Requires Config.VoiceDetection.Enabled.
+func (p *Processor) LastVoiceProbability() float32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return 0
+	}
+	return p.handle.LastVoiceProbability()
+}
+
+// LastRmsDbfs returns the level estimator's RMS level, in dBFS, for the last
+// processed capture frame. Requires Config.LevelEstimator.Enabled.
+func (p *Processor) LastRmsDbfs() float32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return 0
+	}
+	return p.handle.LastRmsDbfs()
+}
+
 // SetOutputMuted signals that output will be muted (hint for AEC/AGC)
 func (p *Processor) SetOutputMuted(muted bool) {
 	p.mu.Lock()
@@ -218,6 +289,45 @@ func (p *Processor) SetKeyPressed(pressed bool) {
 	}
 }
 
+// SetTransientSuppressionEnabled toggles the standalone transient suppressor
+// at runtime. See Config.TransientSuppressionEnabled.
+func (p *Processor) SetTransientSuppressionEnabled(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle != nil {
+		p.handle.SetTransientSuppressionEnabled(enabled)
+	}
+}
+
+// LastTransientDetected reports whether a transient (keypress/mouse-click/
+// tap) was detected and removed from the last processed capture frame, for
+// UIs that want to show a "typing detected" indicator. Requires
+// Config.TransientSuppressionEnabled.
+func (p *Processor) LastTransientDetected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return false
+	}
+	return p.handle.LastTransientDetected()
+}
+
+// CaptureChannels returns the channel count the processor was configured
+// with via Config.CaptureChannels, for callers (e.g. livestream) that need
+// to request a matching channel layout from an audio device.
+func (p *Processor) CaptureChannels() int {
+	return p.config.CaptureChannels
+}
+
+// RenderChannels returns the channel count the processor was configured
+// with via Config.RenderChannels, for callers (e.g. livestream) that need
+// to request a matching channel layout from an audio device.
+func (p *Processor) RenderChannels() int {
+	return p.config.RenderChannels
+}
+
 // Close releases resources associated with the processor
 func (p *Processor) Close() error {
 	p.mu.Lock()
@@ -0,0 +1,110 @@
+package apm
+
+/*
+#include <bridge.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// MicPosition is one microphone's coordinates, in meters, within a
+// BeamformingConfig.MicGeometry array. The coordinate system is
+// array-relative; only positions relative to each other matter.
+type MicPosition struct {
+	X, Y, Z float32
+}
+
+// BeamformingConfig installs WebRTC's NonlinearBeamformer ahead of AEC/NS in
+// the capture pipeline, collapsing a microphone array down to a single
+// spatially-filtered channel focused on TargetDirectionRadians. Enabling it
+// requires ProcessCaptureFrameBeamformed (not ProcessCaptureFrame) with
+// numChannels == len(MicGeometry).
+type BeamformingConfig struct {
+	Enabled bool
+	// MicGeometry gives each mic's position, in array order; this order
+	// must match the channel order ProcessCaptureFrameBeamformed is called
+	// with.
+	MicGeometry []MicPosition
+	// TargetDirectionRadians is the look direction the beamformer steers
+	// toward, in radians, using the same convention as
+	// webrtc::SphericalPointf azimuth (0 = the array's forward axis).
+	TargetDirectionRadians float32
+}
+
+// ApplyBeamformingConfig constructs a webrtc::NonlinearBeamformer from
+// cfg.MicGeometry/TargetDirectionRadians and installs it ahead of AEC/NS in
+// h's capture pipeline. It is a no-op if cfg.Enabled is false.
+func (h *Handle) ApplyBeamformingConfig(cfg BeamformingConfig) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.MicGeometry) < 2 {
+		return fmt.Errorf("beamforming: MicGeometry needs at least 2 mics, got %d", len(cfg.MicGeometry))
+	}
+
+	cPositions := make([]C.ApmMicPosition, len(cfg.MicGeometry))
+	for i, m := range cfg.MicGeometry {
+		cPositions[i] = C.ApmMicPosition{
+			x: C.float(m.X),
+			y: C.float(m.Y),
+			z: C.float(m.Z),
+		}
+	}
+
+	result := C.ApplyBeamformerConfig(
+		h.ptr,
+		(*C.ApmMicPosition)(unsafe.Pointer(&cPositions[0])),
+		C.int(len(cPositions)),
+		C.float(cfg.TargetDirectionRadians),
+	)
+
+	if C.is_success(result) == 0 {
+		return fmt.Errorf("failed to apply beamformer config: error code %d", int(result))
+	}
+
+	return nil
+}
+
+// ProcessCaptureFrameBeamformed processes one capture frame from a
+// microphone array through the beamformer installed by
+// ApplyBeamformingConfig (or Config.Beamforming at Create time), ahead of
+// AEC/NS, collapsing numChannels input channels down to a single processed
+// output channel. samples is interleaved, numChannels must equal the
+// BeamformingConfig.MicGeometry length the beamformer was built with.
+func (h *Handle) ProcessCaptureFrameBeamformed(samples []float32, numChannels int) ([]float32, error) {
+	if h.ptr == nil {
+		return nil, fmt.Errorf("audio processor not initialized")
+	}
+
+	expectedLen := numChannels * NumSamplesPerFrame
+	if len(samples) != expectedLen {
+		return nil, fmt.Errorf("expected %d samples, got %d", expectedLen, len(samples))
+	}
+
+	inChannels := deinterleaveFloat32(samples, numChannels)
+	inPtrs := make([]*C.float, len(inChannels))
+	for i, ch := range inChannels {
+		inPtrs[i] = (*C.float)(unsafe.Pointer(&ch[0]))
+	}
+
+	out := make([]float32, NumSamplesPerFrame)
+
+	result := C.ProcessStreamBeamformed(
+		h.ptr,
+		(**C.float)(unsafe.Pointer(&inPtrs[0])),
+		C.int(numChannels),
+		(*C.float)(unsafe.Pointer(&out[0])),
+	)
+
+	if C.is_success(result) == 0 {
+		return nil, fmt.Errorf("failed to process beamformed capture frame: error code %d", int(result))
+	}
+
+	return out, nil
+}
@@ -19,6 +19,8 @@ package apm
 import "C"
 import (
 	"fmt"
+	"sync"
+	"time"
 	"unsafe"
 
 	_ "github.com/CoyAce/apm/google.com/webrtc"
@@ -55,11 +57,37 @@ type CaptureLevelAdjustmentConfig struct {
 	AnalogMicGainEmulation AnalogMicGainEmulationConfig
 }
 
+// AecmRoutingMode selects the device acoustic routing AECM (the fixed-point
+// mobile echo canceler) tunes its filter for, mirroring
+// webrtc::EchoControlMobile::RoutingMode.
+type AecmRoutingMode int
+
+const (
+	AecmRoutingQuietEarpieceOrHeadset AecmRoutingMode = iota
+	AecmRoutingEarpiece
+	AecmRoutingLoudEarpiece
+	AecmRoutingSpeakerphone
+	AecmRoutingLoudSpeakerphone
+)
+
 // EchoCancellationConfig holds echo cancellation settings
 type EchoCancellationConfig struct {
-	Enabled       bool
+	Enabled bool
+	// MobileMode selects WebRTC's fixed-point EchoControlMobile (AECM)
+	// instead of the full-band AEC3 canceler. AECM is far cheaper and is
+	// intended for embedded/ARM targets that can't afford AEC3.
 	MobileMode    bool
 	StreamDelayMs int // nil means use delay-agnostic mode
+
+	// RoutingMode and ComfortNoise only apply when MobileMode is enabled.
+	RoutingMode  AecmRoutingMode
+	ComfortNoise bool
+
+	// Aec3 overrides the full-band AEC3 canceler's tuning (ignored when
+	// MobileMode is set). Nil keeps WebRTC's built-in default tuning; use
+	// DefaultAec3Config as a starting point for a device-specific one. See
+	// ApplyAec3Config.
+	Aec3 *EchoCanceller3Config
 }
 
 // GainControlConfig holds automatic gain control settings
@@ -76,31 +104,101 @@ type NoiseSuppressionConfig struct {
 	SuppressionLevel NsLevel
 }
 
+// VoiceDetectionLikelihood selects how readily VoiceDetection reports speech,
+// mirroring the old audio_processing.h VoiceDetection::Likelihood enum: a
+// higher likelihood requires stronger evidence before a frame is flagged as
+// speech, trading false positives for missed onsets.
+type VoiceDetectionLikelihood int
+
+const (
+	VoiceDetectionVeryLowLikelihood VoiceDetectionLikelihood = iota
+	VoiceDetectionLowLikelihood
+	VoiceDetectionModerateLikelihood
+	VoiceDetectionHighLikelihood
+)
+
+// VoiceDetectionConfig enables WebRTC's standalone VoiceDetection submodule,
+// surfaced via Handle.LastVoiceProbability and Stats.SpeechDetected. This is
+// independent of LevelEstimatorConfig/Stats.SpeechProbability, which comes
+// from the newer level estimator instead.
+type VoiceDetectionConfig struct {
+	Enabled    bool
+	Likelihood VoiceDetectionLikelihood
+}
+
 // Config holds all runtime configuration options
 type Config struct {
 	CaptureLevelAdjustment CaptureLevelAdjustmentConfig
 	EchoCancellation       EchoCancellationConfig
 	GainControl            GainControlConfig
 	NoiseSuppression       NoiseSuppressionConfig
+	LevelEstimator         LevelEstimatorConfig
+	VoiceDetection         VoiceDetectionConfig
+	Beamforming            BeamformingConfig
 	HighPassFilterEnabled  bool
-	CaptureChannels        int
-	RenderChannels         int
+
+	// TransientSuppressionEnabled installs WebRTC's standalone
+	// TransientSuppressor, which actively removes short broadband bursts
+	// (keyboard clacks, mouse clicks, cup taps) from the capture signal.
+	// This goes further than SetKeyPressed, which only hints AEC/AGC about
+	// a keypress rather than removing the resulting sound. See
+	// Handle.LastTransientDetected.
+	TransientSuppressionEnabled bool
+
+	CaptureChannels int
+	RenderChannels  int
+
+	// CaptureFormat/RenderFormat describe the sample rate audio is delivered
+	// in at the capture/render edges of the pipeline. A zero SampleRateHz
+	// means audio is already at the native SampleRateHz and no resampling
+	// front-end is installed.
+	CaptureFormat StreamFormat
+	RenderFormat  StreamFormat
 }
 
 // Stats holds statistics from the audio processor
 type Stats struct {
 	ResidualEchoLikelihood    float64
 	DivergentFilterFraction   float64
+	DivergentFilter           bool
 	EchoReturnLoss            float64
 	EchoReturnLossEnhancement float64
 	DelayMedianMs             int
 	DelayStdMs                int
 	DelayMs                   int
+
+	// RmsDbfs and PeakDbfs are the LevelEstimator's RMS and peak level of
+	// the capture signal, in dBFS. Populated only when
+	// LevelEstimatorConfig.Enabled is set.
+	RmsDbfs  float64
+	PeakDbfs float64
+
+	// SpeechProbability is the voice-activity likelihood for the last
+	// processed capture frame, in [0, 1].
+	SpeechProbability float64
+
+	// SpeechDetected is VoiceDetection's speech/non-speech decision for the
+	// last processed capture frame, at the sensitivity configured by
+	// VoiceDetectionConfig.Likelihood. Populated only when
+	// VoiceDetectionConfig.Enabled is set.
+	SpeechDetected bool
+}
+
+// LevelEstimatorConfig controls the RMS/peak level estimator surfaced in
+// Stats.RmsDbfs/PeakDbfs.
+type LevelEstimatorConfig struct {
+	Enabled bool
 }
 
 // Handle represents an opaque handle to the audio processor
 type Handle struct {
 	ptr C.ApmHandle
+
+	// lastRealRenderAt tracks the last time the application (as opposed to
+	// an attached RenderSource) supplied a render frame. See
+	// AttachRenderSource.
+	renderMu         sync.Mutex
+	lastRealRenderAt time.Time
 }
 
 // Create creates a new audio processor with the given initialization config
@@ -113,7 +211,23 @@ func Create(config Config) (*Handle, error) {
 		return nil, fmt.Errorf("failed to create audio processor: error code %d", int(errorCode))
 	}
 
-	return &Handle{ptr: ptr}, nil
+	h := &Handle{ptr: ptr}
+
+	if aec3 := config.EchoCancellation.Aec3; aec3 != nil {
+		if err := h.ApplyAec3Config(*aec3); err != nil {
+			h.Destroy()
+			return nil, err
+		}
+	}
+
+	if config.Beamforming.Enabled {
+		if err := h.ApplyBeamformingConfig(config.Beamforming); err != nil {
+			h.Destroy()
+			return nil, err
+		}
+	}
+
+	return h, nil
 }
 
 func (h *Handle) Initialize() {
@@ -155,9 +269,11 @@ func parseConfig(config Config) C.ApmConfig {
 			},
 		},
 		echo_cancellation: C.ApmEchoCancellation{
-			enabled:      C.bool(config.EchoCancellation.Enabled),
-			mobile_mode:  C.bool(config.EchoCancellation.MobileMode),
-			stream_delay: C.int(config.EchoCancellation.StreamDelayMs),
+			enabled:       C.bool(config.EchoCancellation.Enabled),
+			mobile_mode:   C.bool(config.EchoCancellation.MobileMode),
+			stream_delay:  C.int(config.EchoCancellation.StreamDelayMs),
+			routing_mode:  C.AecmRoutingMode(config.EchoCancellation.RoutingMode),
+			comfort_noise: C.bool(config.EchoCancellation.ComfortNoise),
 		},
 		gain_control: C.ApmGainControl{
 			enabled:                         C.bool(config.GainControl.Enabled),
@@ -169,7 +285,15 @@ func parseConfig(config Config) C.ApmConfig {
 			enabled:           C.bool(config.NoiseSuppression.Enabled),
 			suppression_level: C.NsLevel(config.NoiseSuppression.SuppressionLevel),
 		},
-		high_pass_filter_enabled: C.bool(config.HighPassFilterEnabled),
+		level_estimator: C.ApmLevelEstimator{
+			enabled: C.bool(config.LevelEstimator.Enabled),
+		},
+		voice_detection: C.ApmVoiceDetection{
+			enabled:    C.bool(config.VoiceDetection.Enabled),
+			likelihood: C.VoiceDetectionLikelihood(config.VoiceDetection.Likelihood),
+		},
+		high_pass_filter_enabled:      C.bool(config.HighPassFilterEnabled),
+		transient_suppression_enabled: C.bool(config.TransientSuppressionEnabled),
 	}
 	return cConfig
 }
@@ -199,6 +323,98 @@ func (h *Handle) ProcessCaptureFrame(samples []float32, numChannels int) error {
 	return nil
 }
 
+// ProcessCaptureFramePlanar processes a capture (microphone) frame given as
+// one []float32 per channel (WebRTC AudioBuffer's native layout), such as
+// the non-interleaved buffers PortAudio hands out when opened with
+// portaudio.StreamParameters{Flags: portaudio.NoFlag}. Unlike
+// ProcessCaptureFrame, channels is passed straight through to the
+// underlying AudioBuffer without an intermediate interleave copy. Each
+// channel must have NumSamplesPerFrame samples.
+func (h *Handle) ProcessCaptureFramePlanar(channels [][]float32) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("no channels provided")
+	}
+
+	ptrs := make([]*C.float, len(channels))
+	for i, ch := range channels {
+		if len(ch) != NumSamplesPerFrame {
+			return fmt.Errorf("channel %d: expected %d samples, got %d", i, NumSamplesPerFrame, len(ch))
+		}
+		ptrs[i] = (*C.float)(unsafe.Pointer(&ch[0]))
+	}
+
+	result := C.ProcessStreamPlanar(
+		h.ptr,
+		(**C.float)(unsafe.Pointer(&ptrs[0])),
+		C.int(len(channels)),
+	)
+
+	if C.is_success(result) == 0 {
+		return fmt.Errorf("failed to process capture frame: error code %d", int(result))
+	}
+
+	return nil
+}
+
+// ProcessCaptureFrameConfig processes one 10 ms capture frame at arbitrary,
+// independent input/output sample rates and channel counts, mirroring
+// webrtc::AudioProcessing::ProcessStream(const float* const*, StreamConfig,
+// StreamConfig, float* const*). Unlike ProcessCaptureFrame/
+// ProcessCaptureFramePlanar, which require both sides to match the fixed
+// native SampleRateHz, APM itself resamples/remixes between in and out, so
+// e.g. a 48 kHz mic can be processed straight through to 16 kHz output for
+// an ASR pipeline with no separate Go resampling front-end. samples is
+// interleaved at in's rate/channel count; frames must span exactly 10 ms
+// (in.SampleRateHz/100 samples per channel) - use a CaptureRing to adapt
+// arbitrary buffer sizes into that framing.
+func (h *Handle) ProcessCaptureFrameConfig(samples []float32, in, out StreamConfig) ([]float32, error) {
+	if h.ptr == nil {
+		return nil, fmt.Errorf("audio processor not initialized")
+	}
+
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	if err := out.validate(); err != nil {
+		return nil, err
+	}
+
+	expectedLen := in.NumChannels * in.samplesPerFrame()
+	if len(samples) != expectedLen {
+		return nil, fmt.Errorf("expected %d samples, got %d", expectedLen, len(samples))
+	}
+
+	inChannels := deinterleaveFloat32(samples, in.NumChannels)
+	inPtrs := make([]*C.float, len(inChannels))
+	for i, ch := range inChannels {
+		inPtrs[i] = (*C.float)(unsafe.Pointer(&ch[0]))
+	}
+
+	outChannels := make([][]float32, out.NumChannels)
+	outPtrs := make([]*C.float, out.NumChannels)
+	for i := range outChannels {
+		outChannels[i] = make([]float32, out.samplesPerFrame())
+		outPtrs[i] = (*C.float)(unsafe.Pointer(&outChannels[i][0]))
+	}
+
+	result := C.ProcessStreamEx(
+		h.ptr,
+		(**C.float)(unsafe.Pointer(&inPtrs[0])),
+		in.toC(),
+		out.toC(),
+		(**C.float)(unsafe.Pointer(&outPtrs[0])),
+	)
+
+	if C.is_success(result) == 0 {
+		return nil, fmt.Errorf("failed to process capture frame: error code %d", int(result))
+	}
+
+	return interleaveFloat32(outChannels), nil
+}
+
 func (h *Handle) ProcessCaptureIntFrame(samples []int16, numChannels int) error {
 	if h.ptr == nil {
 		return fmt.Errorf("audio processor not initialized")
@@ -244,9 +460,107 @@ func (h *Handle) ProcessRenderFrame(samples []float32, numChannels int) error {
 		return fmt.Errorf("failed to process render frame: error code %d", int(result))
 	}
 
+	h.renderMu.Lock()
+	h.lastRealRenderAt = time.Now()
+	h.renderMu.Unlock()
+
 	return nil
 }
 
+// ProcessRenderFramePlanar processes a render (speaker) frame given as one
+// []float32 per channel for echo cancellation, passing channels straight
+// through to the underlying AudioBuffer without an intermediate interleave
+// copy. See ProcessCaptureFramePlanar for the buffer layout. Each channel
+// must have NumSamplesPerFrame samples.
+func (h *Handle) ProcessRenderFramePlanar(channels [][]float32) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("no channels provided")
+	}
+
+	ptrs := make([]*C.float, len(channels))
+	for i, ch := range channels {
+		if len(ch) != NumSamplesPerFrame {
+			return fmt.Errorf("channel %d: expected %d samples, got %d", i, NumSamplesPerFrame, len(ch))
+		}
+		ptrs[i] = (*C.float)(unsafe.Pointer(&ch[0]))
+	}
+
+	result := C.ProcessReverseStreamPlanar(
+		h.ptr,
+		(**C.float)(unsafe.Pointer(&ptrs[0])),
+		C.int(len(channels)),
+	)
+
+	if C.is_success(result) == 0 {
+		return fmt.Errorf("failed to process render frame: error code %d", int(result))
+	}
+
+	h.renderMu.Lock()
+	h.lastRealRenderAt = time.Now()
+	h.renderMu.Unlock()
+
+	return nil
+}
+
+// ProcessRenderFrameConfig is ProcessCaptureFrameConfig's render-path
+// counterpart: it feeds one 10 ms render (speaker) frame through
+// webrtc::AudioProcessing::ProcessReverseStream with independent in/out
+// StreamConfigs, returning whatever output APM produces for out (used when
+// the render reference needs reformatting, e.g. for a downstream beamformer
+// channel layout). Most callers that only need echo-cancellation reference
+// audio fed in can ignore the returned slice.
+func (h *Handle) ProcessRenderFrameConfig(samples []float32, in, out StreamConfig) ([]float32, error) {
+	if h.ptr == nil {
+		return nil, fmt.Errorf("audio processor not initialized")
+	}
+
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	if err := out.validate(); err != nil {
+		return nil, err
+	}
+
+	expectedLen := in.NumChannels * in.samplesPerFrame()
+	if len(samples) != expectedLen {
+		return nil, fmt.Errorf("expected %d samples, got %d", expectedLen, len(samples))
+	}
+
+	inChannels := deinterleaveFloat32(samples, in.NumChannels)
+	inPtrs := make([]*C.float, len(inChannels))
+	for i, ch := range inChannels {
+		inPtrs[i] = (*C.float)(unsafe.Pointer(&ch[0]))
+	}
+
+	outChannels := make([][]float32, out.NumChannels)
+	outPtrs := make([]*C.float, out.NumChannels)
+	for i := range outChannels {
+		outChannels[i] = make([]float32, out.samplesPerFrame())
+		outPtrs[i] = (*C.float)(unsafe.Pointer(&outChannels[i][0]))
+	}
+
+	result := C.ProcessReverseStreamEx(
+		h.ptr,
+		(**C.float)(unsafe.Pointer(&inPtrs[0])),
+		in.toC(),
+		out.toC(),
+		(**C.float)(unsafe.Pointer(&outPtrs[0])),
+	)
+
+	if C.is_success(result) == 0 {
+		return nil, fmt.Errorf("failed to process render frame: error code %d", int(result))
+	}
+
+	h.renderMu.Lock()
+	h.lastRealRenderAt = time.Now()
+	h.renderMu.Unlock()
+
+	return interleaveFloat32(outChannels), nil
+}
+
 func (h *Handle) ProcessRenderIntFrame(samples []int16, numChannels int) error {
 	if h.ptr == nil {
 		return fmt.Errorf("audio processor not initialized")
@@ -282,15 +596,39 @@ func (h *Handle) GetStats() Stats {
 
 	stats.ResidualEchoLikelihood = float64(cStats.residual_echo_likelihood)
 	stats.DivergentFilterFraction = float64(cStats.divergent_filter_fraction)
+	stats.DivergentFilter = bool(cStats.divergent_filter)
 	stats.EchoReturnLoss = float64(cStats.echo_return_loss)
 	stats.EchoReturnLossEnhancement = float64(cStats.echo_return_loss_enhancement)
 	stats.DelayMedianMs = int(cStats.delay_median_ms)
 	stats.DelayStdMs = int(cStats.delay_std_ms)
 	stats.DelayMs = int(cStats.delay_ms)
+	stats.RmsDbfs = float64(cStats.rms_dbfs)
+	stats.PeakDbfs = float64(cStats.peak_dbfs)
+	stats.SpeechProbability = float64(cStats.speech_probability)
+	stats.SpeechDetected = bool(cStats.speech_detected)
 
 	return stats
 }
 
+// LastVoiceProbability returns VoiceDetection's speech-activity probability
+// for the last processed capture frame, in [0, 1]. Requires
+// VoiceDetectionConfig.Enabled.
+func (h *Handle) LastVoiceProbability() float32 {
+	if h.ptr == nil {
+		return 0
+	}
+	return float32(C.get_voice_probability(h.ptr))
+}
+
+// LastRmsDbfs returns the level estimator's RMS level, in dBFS, for the last
+// processed capture frame. Requires LevelEstimatorConfig.Enabled.
+func (h *Handle) LastRmsDbfs() float32 {
+	if h.ptr == nil {
+		return 0
+	}
+	return float32(C.get_rms_dbfs(h.ptr))
+}
+
 func (h *Handle) SetStreamAnalogLevel(level int) {
 	if h.ptr == nil {
 		return
@@ -336,6 +674,79 @@ func (h *Handle) SetStreamKeyPressed(pressed bool) {
 	C.set_stream_key_pressed(h.ptr, C.bool(pressed))
 }
 
+// SetTransientSuppressionEnabled toggles WebRTC's standalone
+// TransientSuppressor at runtime, without requiring a full ApplyConfig
+// round-trip. See Config.TransientSuppressionEnabled.
+func (h *Handle) SetTransientSuppressionEnabled(enabled bool) {
+	if h.ptr == nil {
+		return
+	}
+	C.apm_set_transient_suppression(h.ptr, C.bool(enabled))
+}
+
+// LastTransientDetected reports whether TransientSuppressor flagged and
+// removed a transient (keypress/mouse-click/tap) in the last processed
+// capture frame. Requires Config.TransientSuppressionEnabled.
+func (h *Handle) LastTransientDetected() bool {
+	if h.ptr == nil {
+		return false
+	}
+	return bool(C.get_transient_detected(h.ptr))
+}
+
+// StartAecDump starts the native AudioProcessing debug dump like
+// AttachAecDump, but bounds the file to maxSizeBytes via
+// AudioProcessingBuilder's max_log_size_bytes (mirrored by the bridge's
+// Create/AttachAecDumpForFile), so a long-running capture rotates instead
+// of growing without limit. A non-positive maxSizeBytes means no limit.
+func (h *Handle) StartAecDump(path string, maxSizeBytes int64) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var errorCode C.int
+	if C.AttachAecDumpForFile(h.ptr, cPath, C.int64_t(maxSizeBytes), &errorCode) == 0 {
+		return fmt.Errorf("failed to start aec dump: error code %d", int(errorCode))
+	}
+	return nil
+}
+
+// StopAecDump stops a dump started with StartAecDump, flushing and closing
+// the underlying file. It is equivalent to DetachAecDump.
+func (h *Handle) StopAecDump() {
+	h.DetachAecDump()
+}
+
+// AttachAecDump starts the native AudioProcessing debug dump, writing a
+// length-delimited protobuf Event stream to path that can be replayed with
+// WebRTC's audioproc_f tool. It mirrors AudioProcessing::AttachAecDump.
+func (h *Handle) AttachAecDump(path string) error {
+	if h.ptr == nil {
+		return fmt.Errorf("audio processor not initialized")
+	}
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var errorCode C.int
+	if C.AttachAecDump(h.ptr, cPath, &errorCode) == 0 {
+		return fmt.Errorf("failed to attach aec dump: error code %d", int(errorCode))
+	}
+	return nil
+}
+
+// DetachAecDump stops a dump previously started with AttachAecDump, flushing
+// and closing the underlying file.
+func (h *Handle) DetachAecDump() {
+	if h.ptr == nil {
+		return
+	}
+	C.DetachAecDump(h.ptr)
+}
+
 // GetNumSamplesPerFrame returns the number of samples per frame
 func GetNumSamplesPerFrame() int {
 	return int(C.get_num_samples_per_frame())
@@ -208,6 +208,25 @@ func TestSetConfigWithStreamDelay(t *testing.T) {
 	defer h.Destroy()
 }
 
+func TestSetConfigAecMobileMode(t *testing.T) {
+	config := Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+		EchoCancellation: EchoCancellationConfig{
+			Enabled:      true,
+			MobileMode:   true,
+			RoutingMode:  AecmRoutingSpeakerphone,
+			ComfortNoise: true,
+		},
+	}
+
+	h, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+}
+
 // =============================================================================
 // Processing Tests
 // =============================================================================
@@ -257,6 +276,49 @@ func TestProcessCaptureFrameStereo(t *testing.T) {
 	}
 }
 
+func TestProcessCaptureFramePlanar(t *testing.T) {
+	config := Config{
+		CaptureChannels: 2,
+		RenderChannels:  2,
+	}
+
+	h, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	channels := [][]float32{
+		generateSineWave(440, 0.5, NumSamplesPerFrame),
+		generateSineWave(880, 0.3, NumSamplesPerFrame),
+	}
+
+	err = h.ProcessCaptureFramePlanar(channels)
+	if err != nil {
+		t.Fatalf("ProcessCaptureFramePlanar failed: %v", err)
+	}
+}
+
+func TestProcessCaptureFramePlanarWrongSampleCount(t *testing.T) {
+	config := Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+	}
+
+	h, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	channels := [][]float32{make([]float32, NumSamplesPerFrame/2)}
+
+	err = h.ProcessCaptureFramePlanar(channels)
+	if err == nil {
+		t.Error("ProcessCaptureFramePlanar should fail with wrong sample count")
+	}
+}
+
 func TestProcessRenderFrame(t *testing.T) {
 	config := Config{
 		CaptureChannels: 1,
@@ -280,6 +342,32 @@ func TestProcessRenderFrame(t *testing.T) {
 	}
 }
 
+func TestProcessRenderFramePlanar(t *testing.T) {
+	config := Config{
+		CaptureChannels: 2,
+		RenderChannels:  2,
+		EchoCancellation: EchoCancellationConfig{
+			Enabled: true,
+		},
+	}
+
+	h, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	channels := [][]float32{
+		generateSineWave(1000, 0.4, NumSamplesPerFrame),
+		generateSineWave(2000, 0.2, NumSamplesPerFrame),
+	}
+
+	err = h.ProcessRenderFramePlanar(channels)
+	if err != nil {
+		t.Fatalf("ProcessRenderFramePlanar failed: %v", err)
+	}
+}
+
 func TestProcessRenderAndCapture(t *testing.T) {
 	config := Config{
 		CaptureChannels: 1,
@@ -357,6 +445,31 @@ func TestProcessCaptureFrameWrongSampleCount(t *testing.T) {
 // Statistics Tests
 // =============================================================================
 
+func TestGetStatsWithLevelEstimator(t *testing.T) {
+	config := Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+		LevelEstimator: LevelEstimatorConfig{
+			Enabled: true,
+		},
+	}
+
+	h, err := Create(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	samples := generateSineWave(440, 0.5, NumSamplesPerFrame)
+	if err := h.ProcessCaptureFrame(samples, 1); err != nil {
+		t.Fatalf("ProcessCaptureFrame failed: %v", err)
+	}
+
+	stats := h.GetStats()
+	t.Logf("RMS: %.2f dBFS, peak: %.2f dBFS, speech probability: %.2f",
+		stats.RmsDbfs, stats.PeakDbfs, stats.SpeechProbability)
+}
+
 func TestGetStatsWithAEC(t *testing.T) {
 	config := Config{
 		CaptureChannels: 1,
@@ -495,6 +608,15 @@ func TestNsLevelValues(t *testing.T) {
 	}
 }
 
+func TestVoiceDetectionLikelihoodValues(t *testing.T) {
+	if VoiceDetectionVeryLowLikelihood != 0 {
+		t.Errorf("VoiceDetectionVeryLowLikelihood = %d, want 0", VoiceDetectionVeryLowLikelihood)
+	}
+	if VoiceDetectionHighLikelihood != 3 {
+		t.Errorf("VoiceDetectionHighLikelihood = %d, want 3", VoiceDetectionHighLikelihood)
+	}
+}
+
 func TestAgcModeValues(t *testing.T) {
 	if AgcModeAdaptiveAnalog != 0 {
 		t.Errorf("AgcModeAdaptiveAnalog = %d, want 0", AgcModeAdaptiveAnalog)
@@ -504,6 +626,15 @@ func TestAgcModeValues(t *testing.T) {
 	}
 }
 
+func TestAecmRoutingModeValues(t *testing.T) {
+	if AecmRoutingQuietEarpieceOrHeadset != 0 {
+		t.Errorf("AecmRoutingQuietEarpieceOrHeadset = %d, want 0", AecmRoutingQuietEarpieceOrHeadset)
+	}
+	if AecmRoutingLoudSpeakerphone != 4 {
+		t.Errorf("AecmRoutingLoudSpeakerphone = %d, want 4", AecmRoutingLoudSpeakerphone)
+	}
+}
+
 func TestVadLikelihoodValues(t *testing.T) {
 	if VadLikelihoodVeryLow != 0 {
 		t.Errorf("VadLikelihoodVeryLow = %d, want 0", VadLikelihoodVeryLow)
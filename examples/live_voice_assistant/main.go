@@ -0,0 +1,60 @@
+// Example: Live voice-assistant loop
+//
+// This example demonstrates a true live microphone-cleaner loop using the
+// livestream sub-package: speaker playback is fed to ProcessRender and
+// microphone input is fed to ProcessCapture in real time over PortAudio,
+// rather than processing pre-recorded files.
+//
+// Usage:
+//
+//	go run main.go
+package main
+
+import (
+	"log"
+
+	"github.com/CoyAce/apm"
+	"github.com/CoyAce/apm/livestream"
+	"github.com/gordonklaus/portaudio"
+)
+
+func main() {
+	if err := portaudio.Initialize(); err != nil {
+		log.Fatalf("Failed to initialize PortAudio: %v", err)
+	}
+	defer portaudio.Terminate()
+
+	processor, err := apm.New(apm.Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+		EchoCancellation: apm.EchoCancellationConfig{
+			Enabled: true,
+		},
+		NoiseSuppression: apm.NoiseSuppressionConfig{
+			Enabled:          true,
+			SuppressionLevel: apm.NsLevelHigh,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	stream, err := livestream.New(processor, livestream.DeviceConfig{
+		SampleRate:      float64(apm.SampleRateHz),
+		FramesPerBuffer: apm.NumSamplesPerFrame,
+	})
+	if err != nil {
+		log.Fatalf("Failed to start live stream: %v", err)
+	}
+	defer stream.Close()
+
+	go func() {
+		for cleaned := range stream.Output() {
+			_ = cleaned // hand off to a VAD/ASR pipeline here
+		}
+	}()
+
+	log.Println("Listening. Press Ctrl+C to stop.")
+	select {} // run forever
+}
@@ -0,0 +1,237 @@
+// Package livestream wires an apm.Processor to a live duplex PortAudio
+// stream, turning it into a running microphone-cleaning/voice-assistant
+// loop instead of the file-to-file pipelines in examples/.
+package livestream
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/CoyAce/apm"
+	"github.com/gordonklaus/portaudio"
+)
+
+// renderQueueCapacity bounds how many samples of queued playback audio
+// PlayRender will buffer before blocking, so a slow consumer can't grow
+// memory unbounded.
+const renderQueueCapacity = 48000 // ~1s at the native sample rate
+
+// outQueueFrames bounds how many cleaned capture frames Output will buffer
+// before the callback starts dropping them.
+const outQueueFrames = 32
+
+// DeviceConfig selects the input/output devices and stream parameters a
+// Stream opens. Leaving InputDevice/OutputDevice nil uses PortAudio's
+// default devices for the host API.
+type DeviceConfig struct {
+	InputDevice     *portaudio.DeviceInfo
+	OutputDevice    *portaudio.DeviceInfo
+	SampleRate      float64
+	FramesPerBuffer int
+}
+
+// Stream is a running duplex PortAudio stream feeding an apm.Processor: the
+// audio it plays out is fed to ProcessRender, the microphone input it
+// captures is fed to ProcessCapture, and the cleaned result is delivered to
+// whatever sink was configured with SetOutputWriter/Output.
+//
+// PortAudio's FramesPerBuffer need not match apm.NumSamplesPerFrame: the
+// callback accumulates capture/render audio across calls (the same way
+// paio.Session does) and only hands APM exact 10 ms native frames.
+type Stream struct {
+	processor *apm.Processor
+	stream    *portaudio.Stream
+
+	mu          sync.Mutex
+	renderQueue chan float32
+	out         chan []float32
+	writer      io.Writer
+
+	captureAccum []float32
+	renderAccum  []float32
+}
+
+// New opens and starts a duplex PortAudio stream driving processor.
+// processor's CaptureChannels/RenderChannels determine the channel count
+// requested from PortAudio.
+func New(processor *apm.Processor, config DeviceConfig) (*Stream, error) {
+	framesPerBuffer := config.FramesPerBuffer
+	if framesPerBuffer == 0 {
+		framesPerBuffer = apm.NumSamplesPerFrame
+	}
+
+	s := &Stream{
+		processor:   processor,
+		renderQueue: make(chan float32, renderQueueCapacity),
+		out:         make(chan []float32, outQueueFrames),
+	}
+
+	inputDevice := config.InputDevice
+	if inputDevice == nil {
+		var err error
+		inputDevice, err = portaudio.DefaultInputDevice()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve default input device: %w", err)
+		}
+	}
+	outputDevice := config.OutputDevice
+	if outputDevice == nil {
+		var err error
+		outputDevice, err = portaudio.DefaultOutputDevice()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve default output device: %w", err)
+		}
+	}
+
+	captureChannels := processor.CaptureChannels()
+	renderChannels := processor.RenderChannels()
+	if captureChannels > inputDevice.MaxInputChannels {
+		return nil, fmt.Errorf("input device %q supports at most %d channels, processor requires %d", inputDevice.Name, inputDevice.MaxInputChannels, captureChannels)
+	}
+	if renderChannels > outputDevice.MaxOutputChannels {
+		return nil, fmt.Errorf("output device %q supports at most %d channels, processor requires %d", outputDevice.Name, outputDevice.MaxOutputChannels, renderChannels)
+	}
+
+	params := portaudio.LowLatencyParameters(inputDevice, outputDevice)
+	params.Input.Channels = captureChannels
+	params.Output.Channels = renderChannels
+	if config.SampleRate != 0 {
+		params.SampleRate = config.SampleRate
+	}
+	params.FramesPerBuffer = framesPerBuffer
+
+	stream, err := portaudio.OpenStream(params, s.callback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open portaudio stream: %w", err)
+	}
+	s.stream = stream
+
+	if err := stream.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start portaudio stream: %w", err)
+	}
+
+	return s, nil
+}
+
+// PlayRender enqueues samples to be played out the speaker and, as they are
+// played, fed to ProcessRender as the echo-cancellation reference. It
+// blocks if the internal queue is full.
+func (s *Stream) PlayRender(samples []float32) {
+	for _, v := range samples {
+		s.renderQueue <- v
+	}
+}
+
+// SetOutputWriter sets an io.Writer that receives cleaned capture audio as
+// it's processed. It is mutually exclusive in practice with reading from
+// Output, though both are serviced if set.
+func (s *Stream) SetOutputWriter(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer = w
+}
+
+// Output returns the channel cleaned capture frames are delivered on.
+func (s *Stream) Output() <-chan []float32 {
+	return s.out
+}
+
+func (s *Stream) callback(in, out []float32) {
+	info := s.stream.Info()
+	latencyMs := int((info.InputLatency + info.OutputLatency).Seconds() * 1000)
+	s.processor.SetStreamDelay(latencyMs)
+
+	s.fillRenderOutput(out)
+	s.processCapture(in)
+}
+
+// fillRenderOutput pulls queued playback samples into out (zero-filling any
+// shortfall) and runs the same samples through ProcessRender in
+// apm.NumSamplesPerFrame-sized chunks, accumulating across calls when
+// len(out) isn't a multiple of the native frame size.
+func (s *Stream) fillRenderOutput(out []float32) {
+	for i := range out {
+		select {
+		case v := <-s.renderQueue:
+			out[i] = v
+			s.renderAccum = append(s.renderAccum, v)
+		default:
+			out[i] = 0
+			s.renderAccum = append(s.renderAccum, 0)
+		}
+	}
+
+	frameLen := s.processor.RenderChannels() * apm.NumSamplesPerFrame
+	if frameLen == 0 {
+		return
+	}
+	for len(s.renderAccum) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, s.renderAccum[:frameLen])
+		s.renderAccum = s.renderAccum[frameLen:]
+
+		s.processor.ProcessRender(frame)
+	}
+}
+
+// processCapture accumulates in across calls and runs every complete
+// apm.NumSamplesPerFrame-sized chunk through ProcessCapture, delivering the
+// cleaned result to the configured writer/Output channel.
+func (s *Stream) processCapture(in []float32) {
+	frameLen := s.processor.CaptureChannels() * apm.NumSamplesPerFrame
+	if frameLen == 0 {
+		return
+	}
+
+	s.captureAccum = append(s.captureAccum, in...)
+	for len(s.captureAccum) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, s.captureAccum[:frameLen])
+		s.captureAccum = s.captureAccum[frameLen:]
+
+		cleaned, err := s.processor.ProcessCapture(frame)
+		if err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		w := s.writer
+		s.mu.Unlock()
+
+		if w != nil {
+			buf := make([]byte, len(cleaned)*4)
+			for i, v := range cleaned {
+				putFloat32LE(buf[i*4:], v)
+			}
+			w.Write(buf)
+		}
+
+		select {
+		case s.out <- cleaned:
+		default:
+			// Drop if the consumer isn't keeping up; blocking here would stall
+			// the audio callback.
+		}
+	}
+}
+
+// Close stops and closes the underlying PortAudio stream.
+func (s *Stream) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	if err := s.stream.Stop(); err != nil {
+		return err
+	}
+	return s.stream.Close()
+}
+
+func putFloat32LE(b []byte, v float32) {
+	bits := math.Float32bits(v)
+	b[0] = byte(bits)
+	b[1] = byte(bits >> 8)
+	b[2] = byte(bits >> 16)
+	b[3] = byte(bits >> 24)
+}
@@ -0,0 +1,265 @@
+// Package opus plugs libopus/libopusenc into the APM pipeline, so VoIP
+// callers can go from network Opus packets -> APM render reference ->
+// cleaned mic -> Opus packets without hand-rolling the PCM/Opus glue.
+package opus
+
+/*
+#cgo pkg-config: opus opusenc opusfile
+#include <opus/opus.h>
+#include <opus/opusenc.h>
+#include <opus/opusfile.h>
+#include <stdlib.h>
+
+extern int goOpusEncWrite(void *user_data, const unsigned char *ptr, opus_int32 len);
+extern int goOpusEncClose(void *user_data);
+
+static OpusEncCallbacks makeOpusEncCallbacks(void) {
+	OpusEncCallbacks cb;
+	cb.write = goOpusEncWrite;
+	cb.close = goOpusEncClose;
+	return cb;
+}
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/CoyAce/apm"
+)
+
+//export goOpusEncWrite
+func goOpusEncWrite(userData unsafe.Pointer, ptr *C.uchar, length C.opus_int32) C.int {
+	w := cgo.Handle(uintptr(userData)).Value().(io.Writer)
+	if _, err := w.Write(C.GoBytes(unsafe.Pointer(ptr), C.int(length))); err != nil {
+		return -1
+	}
+	return 0
+}
+
+//export goOpusEncClose
+func goOpusEncClose(userData unsafe.Pointer) C.int {
+	return 0
+}
+
+// Application selects libopus's encoder tuning, mirroring the OPUS_APPLICATION_*
+// constants.
+type Application int
+
+const (
+	VoIP     Application = C.OPUS_APPLICATION_VOIP
+	Audio    Application = C.OPUS_APPLICATION_AUDIO
+	LowDelay Application = C.OPUS_APPLICATION_RESTRICTED_LOWDELAY
+)
+
+// Options configures a CaptureEncoder.
+type Options struct {
+	Bitrate     int
+	Application Application
+}
+
+// CaptureEncoder is an io.Writer: callers Write raw interleaved float32 mic
+// PCM, which is run through Handle.ProcessCaptureFrame and then Opus
+// encoded into an Ogg Opus stream on the underlying io.Writer.
+type CaptureEncoder struct {
+	handle   *apm.Handle
+	channels int
+	w        io.Writer
+	wHandle  cgo.Handle
+
+	enc     *C.OggOpusEnc
+	comment *C.OggOpusComments
+
+	pending []float32
+}
+
+// NewCaptureEncoder creates an Ogg Opus encoder that cleans mic audio
+// through h before encoding it to w.
+func NewCaptureEncoder(h *apm.Handle, channels int, w io.Writer, opts Options) (*CaptureEncoder, error) {
+	comment := C.ope_comments_create()
+	if comment == nil {
+		return nil, fmt.Errorf("opus: failed to allocate comments")
+	}
+
+	wHandle := cgo.NewHandle(w)
+
+	callbacks := C.makeOpusEncCallbacks()
+	var errorCode C.int
+	enc := C.ope_encoder_create_callbacks(
+		&callbacks,
+		unsafe.Pointer(uintptr(wHandle)),
+		comment,
+		C.opus_int32(apm.SampleRateHz),
+		C.int(channels),
+		C.int(0), // family
+		&errorCode,
+	)
+	if enc == nil {
+		wHandle.Delete()
+		C.ope_comments_destroy(comment)
+		return nil, fmt.Errorf("opus: failed to create encoder: error code %d", int(errorCode))
+	}
+
+	if opts.Bitrate > 0 {
+		C.ope_encoder_ctl(enc, C.OPE_SET_BITRATE_REQUEST, C.int(opts.Bitrate))
+	}
+	if opts.Application != 0 {
+		C.ope_encoder_ctl(enc, C.OPE_SET_APPLICATION_REQUEST, C.int(opts.Application))
+	}
+
+	return &CaptureEncoder{
+		handle:   h,
+		channels: channels,
+		w:        w,
+		wHandle:  wHandle,
+		enc:      enc,
+		comment:  comment,
+	}, nil
+}
+
+// Write decodes p as interleaved float32 PCM, cleans it through
+// ProcessCaptureFrame in apm.NumSamplesPerFrame chunks, and Opus-encodes
+// the result.
+func (e *CaptureEncoder) Write(p []byte) (int, error) {
+	if len(p)%4 != 0 {
+		return 0, fmt.Errorf("opus: PCM buffer length %d is not a multiple of 4 bytes", len(p))
+	}
+
+	samples := bytesToFloat32(p)
+	e.pending = append(e.pending, samples...)
+
+	frameLen := e.channels * apm.NumSamplesPerFrame
+	for len(e.pending) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, e.pending[:frameLen])
+		e.pending = e.pending[frameLen:]
+
+		if err := e.handle.ProcessCaptureFrame(frame, e.channels); err != nil {
+			return 0, err
+		}
+
+		if C.ope_encoder_write_float(e.enc, (*C.float)(unsafe.Pointer(&frame[0])), C.int(apm.NumSamplesPerFrame)) != 0 {
+			return 0, fmt.Errorf("opus: ope_encoder_write_float failed")
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close drains and destroys the encoder, flushing any final Opus packet.
+func (e *CaptureEncoder) Close() error {
+	C.ope_encoder_drain(e.enc)
+	C.ope_encoder_destroy(e.enc)
+	C.ope_comments_destroy(e.comment)
+	e.wHandle.Delete()
+	return nil
+}
+
+// RenderDecoder decodes an incoming Ogg Opus stream, feeding each decoded
+// frame through Handle.ProcessRenderFrame as the echo-cancellation
+// reference before handing back PCM via Read.
+type RenderDecoder struct {
+	handle   *apm.Handle
+	channels int
+	of       *C.OggOpusFile
+
+	pendingSamples []float32 // decoded samples awaiting a full ProcessRenderFrame-sized chunk
+	pendingOut     []byte    // processed PCM bytes waiting to be Read
+	eof            bool
+}
+
+// NewRenderDecoder opens r as an Ogg Opus stream and decodes it through h's
+// render path.
+func NewRenderDecoder(h *apm.Handle, channels int, r io.Reader) (*RenderDecoder, error) {
+	// libopusfile reads from a file descriptor/callback pair; buffering the
+	// whole stream up front keeps the cgo surface small since this
+	// sub-package targets short PTT/VoIP clips rather than unbounded
+	// live streams.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("opus: failed to read ogg stream: %w", err)
+	}
+
+	var errorCode C.int
+	of := C.op_open_memory((*C.uchar)(unsafe.Pointer(&data[0])), C.size_t(len(data)), &errorCode)
+	if of == nil {
+		return nil, fmt.Errorf("opus: op_open_memory failed: error code %d", int(errorCode))
+	}
+
+	return &RenderDecoder{handle: h, channels: channels, of: of}, nil
+}
+
+// Read decodes Opus audio, accumulating across op_read_float calls (which
+// may return any number of samples) and running ProcessRenderFrame only on
+// exact apm.NumSamplesPerFrame chunks, the same way CaptureStream/
+// RenderStream and paio's accumulators feed APM. It returns interleaved
+// float32 PCM bytes; a final partial frame left at end of stream is
+// dropped rather than processed short.
+func (d *RenderDecoder) Read(p []byte) (int, error) {
+	frameLen := d.channels * apm.NumSamplesPerFrame
+
+	for len(d.pendingOut) == 0 {
+		if !d.eof {
+			buf := make([]float32, frameLen)
+			n := C.op_read_float(d.of, (*C.float)(unsafe.Pointer(&buf[0])), C.int(len(buf)), nil)
+			if n < 0 {
+				return 0, fmt.Errorf("opus: op_read_float failed: error code %d", int(n))
+			}
+			if n == 0 {
+				d.eof = true
+			} else {
+				d.pendingSamples = append(d.pendingSamples, buf[:int(n)*d.channels]...)
+			}
+		}
+
+		for len(d.pendingSamples) >= frameLen {
+			frame := make([]float32, frameLen)
+			copy(frame, d.pendingSamples[:frameLen])
+			d.pendingSamples = d.pendingSamples[frameLen:]
+
+			if err := d.handle.ProcessRenderFrame(frame, d.channels); err != nil {
+				return 0, err
+			}
+			d.pendingOut = append(d.pendingOut, float32ToBytes(frame)...)
+		}
+
+		if d.eof {
+			break
+		}
+	}
+
+	if len(d.pendingOut) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, d.pendingOut)
+	d.pendingOut = d.pendingOut[n:]
+	return n, nil
+}
+
+// Close releases the underlying Ogg Opus file.
+func (d *RenderDecoder) Close() error {
+	C.op_free(d.of)
+	return nil
+}
+
+func bytesToFloat32(b []byte) []float32 {
+	out := make([]float32, len(b)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return out
+}
+
+func float32ToBytes(samples []float32) []byte {
+	out := make([]byte, len(samples)*4)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(s))
+	}
+	return out
+}
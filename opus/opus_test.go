@@ -0,0 +1,79 @@
+package opus
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/CoyAce/apm"
+)
+
+func generateSineWave(frequency float64, amplitude float32, numSamples int) []float32 {
+	samples := make([]float32, numSamples)
+	for i := range samples {
+		samples[i] = amplitude * float32(math.Sin(2*math.Pi*frequency*float64(i)/float64(apm.SampleRateHz)))
+	}
+	return samples
+}
+
+// TestCaptureEncoderRoundTrip verifies that the bytes written to a
+// CaptureEncoder actually reach its underlying io.Writer as a decodable Ogg
+// Opus stream, rather than being silently discarded.
+func TestCaptureEncoderRoundTrip(t *testing.T) {
+	const channels = 1
+	const numFrames = 20
+
+	encHandle, err := apm.Create(apm.Config{CaptureChannels: channels, RenderChannels: channels})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer encHandle.Destroy()
+
+	var ogg bytes.Buffer
+	enc, err := NewCaptureEncoder(encHandle, channels, &ogg, Options{Bitrate: 32000})
+	if err != nil {
+		t.Fatalf("NewCaptureEncoder: %v", err)
+	}
+
+	pcm := float32ToBytes(generateSineWave(440, 0.2, apm.NumSamplesPerFrame*channels*numFrames))
+	if _, err := enc.Write(pcm); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if ogg.Len() == 0 {
+		t.Fatal("no Ogg Opus bytes reached the underlying io.Writer")
+	}
+
+	decHandle, err := apm.Create(apm.Config{CaptureChannels: channels, RenderChannels: channels})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer decHandle.Destroy()
+
+	dec, err := NewRenderDecoder(decHandle, channels, bytes.NewReader(ogg.Bytes()))
+	if err != nil {
+		t.Fatalf("NewRenderDecoder: %v", err)
+	}
+	defer dec.Close()
+
+	var decoded int
+	buf := make([]byte, apm.NumSamplesPerFrame*channels*4)
+	for {
+		n, err := dec.Read(buf)
+		decoded += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+	}
+
+	if decoded == 0 {
+		t.Fatal("decoded no PCM bytes from the round-tripped Ogg Opus stream")
+	}
+}
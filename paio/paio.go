@@ -0,0 +1,222 @@
+// Package paio wires an apm.Handle directly to a duplex PortAudio stream.
+// Unlike livestream (which drives the higher-level apm.Processor), paio
+// talks straight to apm.Handle so callers who already manage their own
+// Processor-equivalent bookkeeping can still get a live microphone-cleaner
+// loop wired up in one call.
+package paio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CoyAce/apm"
+	"github.com/gordonklaus/portaudio"
+)
+
+// renderQueueCapacity bounds how many samples of queued playback audio
+// PlayRender will buffer before blocking.
+const renderQueueCapacity = 48000 // ~1s at the native sample rate
+
+// Options configures a duplex PortAudio session.
+type Options struct {
+	Handle          *apm.Handle
+	CaptureChannels int
+	RenderChannels  int
+	SampleRate      float64
+	FramesPerBuffer int
+
+	// OnCapture, if set, is invoked with every cleaned capture frame
+	// (exactly apm.NumSamplesPerFrame*CaptureChannels samples) instead of
+	// delivering it through Session.Capture(). It is called on the audio
+	// callback goroutine and must not block.
+	OnCapture func([]float32)
+}
+
+// XRunEvent reports an input or output buffer underrun/overrun detected by
+// PortAudio on a callback invocation.
+type XRunEvent struct {
+	Input  bool
+	Output bool
+	Time   time.Time
+}
+
+// Session is a running duplex PortAudio stream feeding an apm.Handle.
+// It owns accumulating/splitting PortAudio's FramesPerBuffer against APM's
+// fixed apm.NumSamplesPerFrame, so FramesPerBuffer need not be 480.
+type Session struct {
+	handle                          *apm.Handle
+	stream                          *portaudio.Stream
+	captureChannels, renderChannels int
+
+	captureAccum []float32
+	renderAccum  []float32
+	renderQueue  chan float32
+
+	out       chan []float32
+	xruns     chan XRunEvent
+	onCapture func([]float32)
+}
+
+// OpenDuplex opens, configures and starts a duplex PortAudio stream
+// feeding opts.Handle.
+func OpenDuplex(opts Options) (*Session, error) {
+	if opts.Handle == nil {
+		return nil, fmt.Errorf("paio: Options.Handle is required")
+	}
+
+	framesPerBuffer := opts.FramesPerBuffer
+	if framesPerBuffer == 0 {
+		framesPerBuffer = apm.NumSamplesPerFrame
+	}
+
+	sampleRate := opts.SampleRate
+	if sampleRate == 0 {
+		sampleRate = float64(apm.SampleRateHz)
+	}
+
+	s := &Session{
+		handle:          opts.Handle,
+		captureChannels: opts.CaptureChannels,
+		renderChannels:  opts.RenderChannels,
+		renderQueue:     make(chan float32, renderQueueCapacity),
+		out:             make(chan []float32, 32),
+		xruns:           make(chan XRunEvent, 8),
+		onCapture:       opts.OnCapture,
+	}
+
+	stream, err := portaudio.OpenDefaultStream(
+		opts.CaptureChannels,
+		opts.RenderChannels,
+		sampleRate,
+		framesPerBuffer,
+		s.callback,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("paio: failed to open portaudio stream: %w", err)
+	}
+	s.stream = stream
+
+	if err := stream.Start(); err != nil {
+		return nil, fmt.Errorf("paio: failed to start portaudio stream: %w", err)
+	}
+
+	return s, nil
+}
+
+// Capture returns the channel cleaned capture frames are delivered on when
+// Options.OnCapture was not set.
+func (s *Session) Capture() <-chan []float32 {
+	return s.out
+}
+
+// XRuns returns the channel input/output buffer underrun/overrun events are
+// reported on.
+func (s *Session) XRuns() <-chan XRunEvent {
+	return s.xruns
+}
+
+// PlayRender enqueues samples to be played through the output device. As
+// PortAudio pulls them into its output buffer they are also fed through
+// ProcessRenderFrame, so APM's echo canceler sees exactly what is played.
+// It blocks if the internal queue is full.
+func (s *Session) PlayRender(samples []float32) {
+	for _, v := range samples {
+		s.renderQueue <- v
+	}
+}
+
+func (s *Session) callback(in, out []float32, _ portaudio.StreamCallbackTimeInfo, flags portaudio.StreamCallbackFlags) {
+	s.reportXRuns(flags)
+
+	info := s.stream.Info()
+	latencyMs := int((info.InputLatency + info.OutputLatency).Seconds() * 1000)
+	s.handle.SetStreamDelayMs(latencyMs)
+
+	s.fillRenderOutput(out)
+	s.processCapture(in)
+}
+
+func (s *Session) reportXRuns(flags portaudio.StreamCallbackFlags) {
+	const xrunFlags = portaudio.InputOverflow | portaudio.InputUnderflow |
+		portaudio.OutputOverflow | portaudio.OutputUnderflow
+	if flags&xrunFlags == 0 {
+		return
+	}
+
+	event := XRunEvent{
+		Input:  flags&(portaudio.InputOverflow|portaudio.InputUnderflow) != 0,
+		Output: flags&(portaudio.OutputOverflow|portaudio.OutputUnderflow) != 0,
+		Time:   time.Now(),
+	}
+	select {
+	case s.xruns <- event:
+	default:
+	}
+}
+
+// fillRenderOutput pulls queued playback samples into out (zero-filling any
+// shortfall) and runs the same samples through ProcessRenderFrame in
+// apm.NumSamplesPerFrame-sized chunks, accumulating across calls when
+// len(out) isn't a multiple of the native frame size.
+func (s *Session) fillRenderOutput(out []float32) {
+	for i := range out {
+		select {
+		case v := <-s.renderQueue:
+			out[i] = v
+			s.renderAccum = append(s.renderAccum, v)
+		default:
+			out[i] = 0
+			s.renderAccum = append(s.renderAccum, 0)
+		}
+	}
+
+	frameLen := s.renderChannels * apm.NumSamplesPerFrame
+	if frameLen == 0 {
+		return
+	}
+	for len(s.renderAccum) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, s.renderAccum[:frameLen])
+		s.renderAccum = s.renderAccum[frameLen:]
+
+		s.handle.ProcessRenderFrame(frame, s.renderChannels)
+	}
+}
+
+func (s *Session) processCapture(in []float32) {
+	captureFrameLen := s.captureChannels * apm.NumSamplesPerFrame
+	if captureFrameLen == 0 {
+		return
+	}
+
+	s.captureAccum = append(s.captureAccum, in...)
+	for len(s.captureAccum) >= captureFrameLen {
+		frame := make([]float32, captureFrameLen)
+		copy(frame, s.captureAccum[:captureFrameLen])
+		s.captureAccum = s.captureAccum[captureFrameLen:]
+
+		if err := s.handle.ProcessCaptureFrame(frame, s.captureChannels); err != nil {
+			continue
+		}
+
+		if s.onCapture != nil {
+			s.onCapture(frame)
+			continue
+		}
+		select {
+		case s.out <- frame:
+		default:
+		}
+	}
+}
+
+// Close stops and closes the underlying PortAudio stream.
+func (s *Session) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	if err := s.stream.Stop(); err != nil {
+		return err
+	}
+	return s.stream.Close()
+}
@@ -0,0 +1,147 @@
+package apm
+
+import (
+	"context"
+	"time"
+)
+
+// pipelineChannelBuffer sizes the internal capture/render/output channels so
+// a producer and Run's consumer loop can run a few frames apart without
+// blocking on each other.
+const pipelineChannelBuffer = 8
+
+// Frame is a timestamped buffer of interleaved audio samples submitted to a
+// Pipeline's capture or render input.
+type Frame struct {
+	Samples   []float32
+	Timestamp time.Time
+}
+
+// ProcessedFrame is the result of running a capture Frame through the
+// pipeline: the processed samples, a stats snapshot taken immediately after,
+// and whether voice activity was detected in the frame.
+type ProcessedFrame struct {
+	Samples       []float32
+	Stats         Stats
+	VoiceDetected bool
+	Timestamp     time.Time
+}
+
+// Pipeline wraps a Processor with a channel-based streaming interface, so
+// callers can push capture/render frames and read processed output without
+// manually pairing render/capture calls or tracking stream delay themselves.
+type Pipeline struct {
+	processor *Processor
+
+	captureIn  chan Frame
+	renderIn   chan Frame
+	captureOut chan ProcessedFrame
+
+	// maxRenderAge bounds how long a queued render frame is kept waiting
+	// for a matching capture frame before it's dropped. Without this, a
+	// render producer that stalls would back up the queue indefinitely.
+	maxRenderAge time.Duration
+
+	renderQueue []Frame
+}
+
+// NewPipeline creates a Pipeline driving processor. processor must not be
+// used directly elsewhere while the pipeline's Run goroutine is active.
+func NewPipeline(processor *Processor) *Pipeline {
+	return &Pipeline{
+		processor:    processor,
+		captureIn:    make(chan Frame, pipelineChannelBuffer),
+		renderIn:     make(chan Frame, pipelineChannelBuffer),
+		captureOut:   make(chan ProcessedFrame, pipelineChannelBuffer),
+		maxRenderAge: 500 * time.Millisecond,
+	}
+}
+
+// CaptureIn returns the channel microphone frames should be sent to.
+func (pl *Pipeline) CaptureIn() chan<- Frame {
+	return pl.captureIn
+}
+
+// RenderIn returns the channel speaker frames should be sent to.
+func (pl *Pipeline) RenderIn() chan<- Frame {
+	return pl.renderIn
+}
+
+// CaptureOut returns the channel processed capture frames are delivered on.
+// It is closed when Run returns.
+func (pl *Pipeline) CaptureOut() <-chan ProcessedFrame {
+	return pl.captureOut
+}
+
+// Run consumes captureIn and renderIn until ctx is canceled or captureIn is
+// closed, emitting processed capture frames on CaptureOut in order. Frames
+// are not re-chunked: each capture and render Frame must hold exactly the
+// one 10 ms frame that Processor.ProcessCapture/ProcessRender accept, and a
+// frame of any other length makes Run return that call's error. It blocks,
+// so callers typically run it in its own goroutine.
+func (pl *Pipeline) Run(ctx context.Context) error {
+	defer close(pl.captureOut)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case rf, ok := <-pl.renderIn:
+			if !ok {
+				pl.renderIn = nil
+				continue
+			}
+			pl.renderQueue = append(pl.renderQueue, rf)
+
+		case cf, ok := <-pl.captureIn:
+			if !ok {
+				return nil
+			}
+			if err := pl.handleCapture(ctx, cf); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (pl *Pipeline) handleCapture(ctx context.Context, cf Frame) error {
+	// Drop render frames that arrived too long ago to plausibly correspond
+	// to this capture frame, rather than ever-growing the queue or pairing
+	// a capture frame with stale far-end audio.
+	for len(pl.renderQueue) > 0 && cf.Timestamp.Sub(pl.renderQueue[0].Timestamp) > pl.maxRenderAge {
+		pl.renderQueue = pl.renderQueue[1:]
+	}
+
+	if len(pl.renderQueue) > 0 {
+		rf := pl.renderQueue[0]
+		pl.renderQueue = pl.renderQueue[1:]
+
+		if err := pl.processor.ProcessRender(rf.Samples); err != nil {
+			return err
+		}
+		if err := pl.processor.SetStreamDelay(int(cf.Timestamp.Sub(rf.Timestamp).Milliseconds())); err != nil {
+			return err
+		}
+	}
+
+	output, err := pl.processor.ProcessCapture(cf.Samples)
+	if err != nil {
+		return err
+	}
+
+	processed := ProcessedFrame{
+		Samples:   output,
+		Stats:     pl.processor.GetStats(),
+		Timestamp: cf.Timestamp,
+	}
+	processed.VoiceDetected = processed.Stats.SpeechProbability > 0.5
+
+	select {
+	case pl.captureOut <- processed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
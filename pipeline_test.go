@@ -0,0 +1,77 @@
+package apm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPipelineProcessesCaptureFrame(t *testing.T) {
+	processor, err := New(Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+		EchoCancellation: EchoCancellationConfig{
+			Enabled: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer processor.Close()
+
+	pipeline := NewPipeline(processor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- pipeline.Run(ctx) }()
+
+	now := time.Now()
+	render := generateSineWave(1000, 0.4, NumSamplesPerFrame)
+	capture := generateSineWave(500, 0.3, NumSamplesPerFrame)
+
+	pipeline.RenderIn() <- Frame{Samples: render, Timestamp: now}
+	pipeline.CaptureIn() <- Frame{Samples: capture, Timestamp: now.Add(20 * time.Millisecond)}
+
+	select {
+	case out := <-pipeline.CaptureOut():
+		if len(out.Samples) != len(capture) {
+			t.Errorf("len(out.Samples) = %d, want %d", len(out.Samples), len(capture))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for processed frame")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestPipelineDropsStaleRenderFrames(t *testing.T) {
+	processor, err := New(Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer processor.Close()
+
+	pipeline := NewPipeline(processor)
+	pipeline.maxRenderAge = 10 * time.Millisecond
+
+	now := time.Now()
+	pipeline.renderQueue = []Frame{{Timestamp: now}}
+
+	capture := generateSineWave(440, 0.5, NumSamplesPerFrame)
+	ctx := context.Background()
+	if err := pipeline.handleCapture(ctx, Frame{Samples: capture, Timestamp: now.Add(time.Second)}); err != nil {
+		t.Fatalf("handleCapture failed: %v", err)
+	}
+
+	if len(pipeline.renderQueue) != 0 {
+		t.Errorf("renderQueue len = %d, want 0 (stale frame should be dropped)", len(pipeline.renderQueue))
+	}
+
+	<-pipeline.CaptureOut()
+}
@@ -0,0 +1,13 @@
+package apm
+
+// Interleave combines one []float32 per channel into a single interleaved
+// buffer (samples[i*len(channels)+ch]), the layout ProcessCapture/
+// ProcessRender expect. Channels are truncated to the shortest one.
+func Interleave(channels [][]float32) []float32 {
+	return interleaveFloat32(channels)
+}
+
+// Deinterleave splits an interleaved buffer into one []float32 per channel.
+func Deinterleave(samples []float32, numChannels int) [][]float32 {
+	return deinterleaveFloat32(samples, numChannels)
+}
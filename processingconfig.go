@@ -0,0 +1,108 @@
+package apm
+
+/*
+#include <bridge.h>
+*/
+import "C"
+
+import "fmt"
+
+// StreamConfig describes the sample rate and channel count of one side of a
+// ProcessStream/ProcessReverseStream call, mirroring webrtc::StreamConfig.
+// Unlike StreamFormat, which configures a Processor's fixed resampling
+// front-end up front, a StreamConfig is passed per call to
+// ProcessCaptureFrameConfig/ProcessRenderFrameConfig and can differ between
+// the input and output side of the same call.
+type StreamConfig struct {
+	SampleRateHz int
+	NumChannels  int
+}
+
+// samplesPerFrame returns the number of samples per channel in a 10 ms frame
+// at this StreamConfig's rate.
+func (s StreamConfig) samplesPerFrame() int {
+	return s.SampleRateHz / (1000 / FrameMs)
+}
+
+// validate rejects a still-zero-value or otherwise malformed StreamConfig
+// before it's used to size/index buffers, so a caller's ordinary mistake
+// (forgetting to set one side of a ProcessCaptureFrameConfig/
+// ProcessRenderFrameConfig call) returns an error instead of panicking deep
+// inside the cgo call path.
+func (s StreamConfig) validate() error {
+	if s.NumChannels <= 0 {
+		return fmt.Errorf("apm: invalid StreamConfig %+v: NumChannels must be > 0", s)
+	}
+	if s.SampleRateHz <= 0 {
+		return fmt.Errorf("apm: invalid StreamConfig %+v: SampleRateHz must be > 0", s)
+	}
+	return nil
+}
+
+func (s StreamConfig) toC() C.ApmStreamConfig {
+	return C.ApmStreamConfig{
+		sample_rate_hz: C.int(s.SampleRateHz),
+		num_channels:   C.int(s.NumChannels),
+	}
+}
+
+// ProcessingConfig groups the four StreamConfigs a full duplex session can
+// run at, mirroring webrtc::ProcessingConfig. It is informational - each of
+// ProcessCaptureFrameConfig/ProcessRenderFrameConfig still takes its own
+// in/out StreamConfig pair - but it's a convenient place for callers to keep
+// all four in sync.
+type ProcessingConfig struct {
+	InputCapture  StreamConfig
+	OutputCapture StreamConfig
+	InputRender   StreamConfig
+	OutputRender  StreamConfig
+}
+
+// CaptureRing adapts arbitrary-sized buffers into the fixed 10 ms frames
+// ProcessCaptureFrameConfig requires, for callers whose device delivers
+// 20 ms/128-sample/etc. buffers rather than exactly 10 ms of audio.
+type CaptureRing struct {
+	handle  *Handle
+	in, out StreamConfig
+	pending []float32 // interleaved input samples awaiting a full in frame
+}
+
+// NewCaptureRing creates a CaptureRing that runs samples through handle at
+// the given in/out StreamConfigs.
+func NewCaptureRing(handle *Handle, in, out StreamConfig) *CaptureRing {
+	return &CaptureRing{handle: handle, in: in, out: out}
+}
+
+// Process appends samples (interleaved at r.in's rate/channel count) to the
+// ring and runs every complete 10 ms frame through
+// ProcessCaptureFrameConfig. The returned slice holds whatever processed
+// output (interleaved at r.out's rate/channel count) is ready, and may be
+// shorter than a frame's worth, or empty, if not enough input has
+// accumulated yet.
+func (r *CaptureRing) Process(samples []float32) ([]float32, error) {
+	if err := r.in.validate(); err != nil {
+		return nil, err
+	}
+	if err := r.out.validate(); err != nil {
+		return nil, err
+	}
+
+	r.pending = append(r.pending, samples...)
+
+	frameLen := r.in.NumChannels * r.in.samplesPerFrame()
+
+	var out []float32
+	for len(r.pending) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, r.pending[:frameLen])
+		r.pending = r.pending[frameLen:]
+
+		processed, err := r.handle.ProcessCaptureFrameConfig(frame, r.in, r.out)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, processed...)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package apm
+
+import "testing"
+
+func TestProcessCaptureFrameConfigZeroOut(t *testing.T) {
+	h, err := Create(Config{CaptureChannels: 1, RenderChannels: 1})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	in := StreamConfig{SampleRateHz: SampleRateHz, NumChannels: 1}
+	samples := generateSineWave(440, 0.5, in.samplesPerFrame())
+
+	if _, err := h.ProcessCaptureFrameConfig(samples, in, StreamConfig{}); err == nil {
+		t.Fatal("ProcessCaptureFrameConfig with zero-value out StreamConfig: expected error, got nil")
+	}
+}
+
+func TestProcessRenderFrameConfigZeroOut(t *testing.T) {
+	h, err := Create(Config{CaptureChannels: 1, RenderChannels: 1})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	in := StreamConfig{SampleRateHz: SampleRateHz, NumChannels: 1}
+	samples := generateSineWave(440, 0.5, in.samplesPerFrame())
+
+	if _, err := h.ProcessRenderFrameConfig(samples, in, StreamConfig{}); err == nil {
+		t.Fatal("ProcessRenderFrameConfig with zero-value out StreamConfig: expected error, got nil")
+	}
+}
+
+func TestCaptureRingProcessZeroOut(t *testing.T) {
+	h, err := Create(Config{CaptureChannels: 1, RenderChannels: 1})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer h.Destroy()
+
+	in := StreamConfig{SampleRateHz: SampleRateHz, NumChannels: 1}
+	ring := NewCaptureRing(h, in, StreamConfig{})
+
+	samples := generateSineWave(440, 0.5, in.samplesPerFrame())
+	if _, err := ring.Process(samples); err == nil {
+		t.Fatal("CaptureRing.Process with zero-value out StreamConfig: expected error, got nil")
+	}
+}
@@ -0,0 +1,143 @@
+package apm
+
+/*
+#include <bridge.h>
+*/
+import "C"
+
+import (
+	"math"
+	"math/rand"
+	"time"
+	"unsafe"
+)
+
+// RenderSource supplies filler far-end audio for AttachRenderSource to feed
+// into ProcessRenderFrame when the application hasn't provided a real
+// render frame recently.
+type RenderSource interface {
+	// NextFrame returns exactly one frame (numChannels * NumSamplesPerFrame
+	// interleaved samples) of filler audio.
+	NextFrame() []float32
+}
+
+// SilenceSource is a RenderSource that produces digital silence. It keeps
+// AEC's delay/filter state fed during hold/mute periods without injecting
+// any energy.
+type SilenceSource struct {
+	channels int
+}
+
+// NewSilenceSource creates a SilenceSource for the given channel count.
+func NewSilenceSource(channels int) *SilenceSource {
+	return &SilenceSource{channels: channels}
+}
+
+// NextFrame returns a frame of zero samples.
+func (s *SilenceSource) NextFrame() []float32 {
+	return make([]float32, s.channels*NumSamplesPerFrame)
+}
+
+// ComfortNoiseSource is a RenderSource that synthesizes low-level shaped
+// noise, modeled on the "silence fetcher" approach of filling gaps with
+// plausible filler audio rather than true silence, which some AEC filters
+// handle worse than a quiet noise floor.
+type ComfortNoiseSource struct {
+	channels        int
+	rms             float32 // linear RMS amplitude derived from rmsDbfs
+	pink            bool
+	rng             *rand.Rand
+	pinkFilterState []float32 // one-pole filter state per channel, for the pink spectrum
+}
+
+// NewComfortNoiseSource creates a comfort-noise generator targeting rmsDbfs
+// (negative dBFS, e.g. -60) of output level. If pink is true the noise is
+// shaped with a simple one-pole low-pass (roughly 1/f) rather than left
+// flat/white.
+func NewComfortNoiseSource(channels int, rmsDbfs float64, pink bool) *ComfortNoiseSource {
+	return &ComfortNoiseSource{
+		channels:        channels,
+		rms:             float32(math.Pow(10, rmsDbfs/20)),
+		pink:            pink,
+		rng:             rand.New(rand.NewSource(1)),
+		pinkFilterState: make([]float32, channels),
+	}
+}
+
+// uniformRmsGain scales uniform noise in [-1, 1), whose RMS is 1/sqrt(3),
+// to unit RMS.
+var uniformRmsGain = float32(math.Sqrt(3))
+
+// pinkAlpha is the one-pole low-pass coefficient used for pink shaping. The
+// filter leaves alpha/(2-alpha) of white noise's variance, which
+// pinkRmsGain restores.
+const pinkAlpha = 0.02
+
+var pinkRmsGain = float32(math.Sqrt((2 - pinkAlpha) / pinkAlpha))
+
+// NextFrame synthesizes one frame of comfort noise.
+func (c *ComfortNoiseSource) NextFrame() []float32 {
+	out := make([]float32, c.channels*NumSamplesPerFrame)
+	for i := 0; i < NumSamplesPerFrame; i++ {
+		for ch := 0; ch < c.channels; ch++ {
+			white := (c.rng.Float32()*2 - 1) * c.rms * uniformRmsGain
+			if c.pink {
+				c.pinkFilterState[ch] += pinkAlpha * (white - c.pinkFilterState[ch])
+				white = c.pinkFilterState[ch] * pinkRmsGain
+			}
+			out[i*c.channels+ch] = white
+		}
+	}
+	return out
+}
+
+// AttachRenderSource starts a goroutine that calls ProcessRenderFrame with
+// src.NextFrame() on a 10 ms ticker whenever the application hasn't
+// supplied a real render frame (via ProcessRenderFrame,
+// ProcessRenderFramePlanar or ProcessRenderFrameConfig) in the last
+// staleAfter; ProcessRenderIntFrame does not reset that timer. This keeps AEC's adaptive filter fed with a
+// plausible reference during far-end mute/hold/packet-loss instead of
+// letting it drift on a stale estimate.
+//
+// The returned stop function stops the goroutine; it must be called to
+// avoid leaking it.
+func (h *Handle) AttachRenderSource(src RenderSource, numChannels int, staleAfter time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(FrameMs * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.renderMu.Lock()
+				stale := time.Since(h.lastRealRenderAt) >= staleAfter
+				h.renderMu.Unlock()
+
+				if stale {
+					h.processRenderFrameSynthetic(src.NextFrame(), numChannels)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// processRenderFrameSynthetic is ProcessRenderFrame without the
+// lastRealRenderAt bookkeeping, so injected filler audio never looks like
+// real application traffic to AttachRenderSource's staleness check.
+func (h *Handle) processRenderFrameSynthetic(samples []float32, numChannels int) error {
+	if h.ptr == nil || len(samples) == 0 {
+		return nil
+	}
+	C.ProcessReverseStream(
+		h.ptr,
+		(*C.float)(unsafe.Pointer(&samples[0])),
+		C.int(numChannels),
+	)
+	return nil
+}
@@ -0,0 +1,44 @@
+package apm
+
+import "testing"
+
+func TestSilenceSourceNextFrame(t *testing.T) {
+	src := NewSilenceSource(2)
+	frame := src.NextFrame()
+
+	if len(frame) != 2*NumSamplesPerFrame {
+		t.Fatalf("len(frame) = %d, want %d", len(frame), 2*NumSamplesPerFrame)
+	}
+	for i, s := range frame {
+		if s != 0 {
+			t.Fatalf("frame[%d] = %f, want 0", i, s)
+		}
+	}
+}
+
+func TestComfortNoiseSourceLevel(t *testing.T) {
+	src := NewComfortNoiseSource(1, -40, false)
+	frame := src.NextFrame()
+
+	if len(frame) != NumSamplesPerFrame {
+		t.Fatalf("len(frame) = %d, want %d", len(frame), NumSamplesPerFrame)
+	}
+
+	var sumSq float64
+	for _, s := range frame {
+		sumSq += float64(s) * float64(s)
+	}
+	rms := sumSq / float64(len(frame))
+	if rms <= 0 {
+		t.Fatal("expected nonzero noise energy")
+	}
+}
+
+func TestComfortNoiseSourcePinkShaping(t *testing.T) {
+	src := NewComfortNoiseSource(1, -30, true)
+	frame := src.NextFrame()
+
+	if len(frame) != NumSamplesPerFrame {
+		t.Fatalf("len(frame) = %d, want %d", len(frame), NumSamplesPerFrame)
+	}
+}
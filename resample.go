@@ -0,0 +1,273 @@
+package apm
+
+import (
+	"fmt"
+	"math"
+)
+
+// StreamFormat describes the sample rate and channel layout audio arrives in
+// (or should be delivered in) at the edge of the pipeline, as opposed to
+// APM's fixed native SampleRateHz. A zero value means "native format".
+type StreamFormat struct {
+	SampleRateHz int
+	NumChannels  int
+}
+
+// resamplerTapsPerPhase controls the quality/cost tradeoff of the polyphase
+// FIR resampler: higher values give a sharper anti-aliasing filter at the
+// cost of more multiply-adds per output sample.
+const resamplerTapsPerPhase = 8
+
+// resampler is a streaming polyphase FIR resampler: a windowed-sinc
+// prototype low-pass filter is split into l precomputed phase banks (one per
+// fractional output position), and a small per-channel ring buffer carries
+// filter history across calls to Process. This is the same design AudioFlinger's
+// dynamic resampler uses to go from arbitrary device rates to a fixed
+// pipeline rate without a large fixed-size buffer.
+type resampler struct {
+	channels int
+	l, m     int // interpolation / decimation factors, reduced by gcd(inRate, outRate)
+
+	phaseCoeffs [][]float32 // [l][resamplerTapsPerPhase]
+	history     [][]float32 // [channels][resamplerTapsPerPhase], oldest first
+	phaseAcc    int
+	partial     []float32 // trailing samples of an incomplete interleaved frame from the last call
+}
+
+func newResampler(inRateHz, outRateHz, channels int) *resampler {
+	g := gcdInt(inRateHz, outRateHz)
+	l := outRateHz / g
+	m := inRateHz / g
+
+	r := &resampler{
+		channels: channels,
+		l:        l,
+		m:        m,
+	}
+
+	r.phaseCoeffs = designPolyphaseFilter(l, m, resamplerTapsPerPhase)
+
+	r.history = make([][]float32, channels)
+	for ch := range r.history {
+		r.history[ch] = make([]float32, resamplerTapsPerPhase)
+	}
+
+	return r
+}
+
+// designPolyphaseFilter builds l polyphase branches of a windowed-sinc
+// low-pass prototype, cut off at the lower of the two rates implied by l/m
+// so that neither upsampling imaging nor downsampling aliasing passes
+// through. The prototype is scaled by l so each phase bank has roughly
+// unity gain.
+func designPolyphaseFilter(l, m, tapsPerPhase int) [][]float32 {
+	n := l * tapsPerPhase
+	cutoff := 1.0 / math.Max(float64(l), float64(m))
+
+	proto := make([]float64, n)
+	center := float64(n-1) / 2
+	for i := 0; i < n; i++ {
+		x := float64(i) - center
+		proto[i] = sinc(cutoff*x) * cutoff * float64(l)
+		// Hamming window
+		proto[i] *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+
+	phases := make([][]float32, l)
+	for p := 0; p < l; p++ {
+		phases[p] = make([]float32, tapsPerPhase)
+		for k := 0; k < tapsPerPhase; k++ {
+			idx := p + k*l
+			if idx < n {
+				phases[p][k] = float32(proto[idx])
+			}
+		}
+	}
+	return phases
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	px := math.Pi * x
+	return math.Sin(px) / px
+}
+
+func gcdInt(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+// Process resamples an interleaved buffer of r.channels channels, returning
+// as many resampled frames as the current input and carried-over filter
+// history allow. Any samples that don't yet amount to a full output frame
+// are retained internally and folded into the next call.
+func (r *resampler) Process(in []float32) []float32 {
+	channels := r.channels
+	if len(r.partial) > 0 {
+		in = append(r.partial, in...)
+		r.partial = nil
+	}
+	nFrames := len(in) / channels
+	if nFrames*channels < len(in) {
+		r.partial = append([]float32(nil), in[nFrames*channels:]...)
+	}
+
+	out := make([]float32, 0, (len(in)*r.l)/r.m+channels)
+	inIdx := 0
+
+	for {
+		for r.phaseAcc >= r.l {
+			if inIdx >= nFrames {
+				return out
+			}
+			for ch := 0; ch < channels; ch++ {
+				copy(r.history[ch], r.history[ch][1:])
+				r.history[ch][resamplerTapsPerPhase-1] = in[inIdx*channels+ch]
+			}
+			inIdx++
+			r.phaseAcc -= r.l
+		}
+
+		coeffs := r.phaseCoeffs[r.phaseAcc]
+		for ch := 0; ch < channels; ch++ {
+			var acc float32
+			hist := r.history[ch]
+			for k, c := range coeffs {
+				acc += hist[k] * c
+			}
+			out = append(out, acc)
+		}
+		r.phaseAcc += r.m
+	}
+}
+
+// latencyMs returns the group delay the FIR filter adds, in milliseconds, at
+// the resampler's output rate.
+func (r *resampler) latencyMs(outRateHz int) float64 {
+	return 1000 * float64(resamplerTapsPerPhase/2) / float64(outRateHz)
+}
+
+func newResamplerPair(externalFormat StreamFormat, nativeChannels int) (in, out *resampler) {
+	if externalFormat.SampleRateHz == 0 || externalFormat.SampleRateHz == SampleRateHz {
+		return nil, nil
+	}
+	in = newResampler(externalFormat.SampleRateHz, SampleRateHz, nativeChannels)
+	out = newResampler(SampleRateHz, externalFormat.SampleRateHz, nativeChannels)
+	return in, out
+}
+
+// ProcessCaptureStream processes a microphone buffer at the configured
+// CaptureFormat rate, internally resampling to/from the native SampleRateHz
+// and accumulating into 10 ms frames. Unlike ProcessCapture, samples may be
+// any length; the returned slice holds whatever processed output is ready
+// and may be shorter (even empty) than the input if not enough samples have
+// accumulated yet for a full frame.
+func (p *Processor) ProcessCaptureStream(samples []float32) ([]float32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return nil, fmt.Errorf("processor is closed")
+	}
+
+	native := samples
+	if p.captureResampleIn != nil {
+		native = p.captureResampleIn.Process(samples)
+	}
+
+	p.capturePending = append(p.capturePending, native...)
+
+	frameLen := p.numChannels * NumSamplesPerFrame
+	var nativeOut []float32
+
+	for len(p.capturePending) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, p.capturePending[:frameLen])
+		p.capturePending = p.capturePending[frameLen:]
+
+		if err := p.handle.ProcessCaptureFrame(frame, p.numChannels); err != nil {
+			return nil, err
+		}
+		nativeOut = append(nativeOut, frame...)
+	}
+
+	if len(nativeOut) == 0 {
+		return nil, nil
+	}
+
+	if p.captureResampleOut != nil {
+		return p.captureResampleOut.Process(nativeOut), nil
+	}
+	return nativeOut, nil
+}
+
+// ProcessRenderStream provides speaker output at the configured RenderFormat
+// rate, resampling and re-chunking it into 10 ms native frames the same way
+// ProcessCaptureStream does for capture. Must be called for the render
+// frame corresponding to a capture frame before ProcessCaptureStream.
+func (p *Processor) ProcessRenderStream(samples []float32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.handle == nil {
+		return fmt.Errorf("processor is closed")
+	}
+
+	native := samples
+	if p.renderResampleIn != nil {
+		native = p.renderResampleIn.Process(samples)
+	}
+
+	p.renderPending = append(p.renderPending, native...)
+
+	frameLen := p.config.RenderChannels * NumSamplesPerFrame
+	for len(p.renderPending) >= frameLen {
+		frame := make([]float32, frameLen)
+		copy(frame, p.renderPending[:frameLen])
+		p.renderPending = p.renderPending[frameLen:]
+
+		if err := p.handle.ProcessRenderFrame(frame, p.config.RenderChannels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FrameLatency returns the additional delay, in milliseconds, that the
+// resampling front-end and 10 ms frame buffering add on the capture path.
+// Callers that need an accurate echo-cancellation delay estimate should add
+// this to their own device-reported latency before calling SetStreamDelay.
+func (p *Processor) FrameLatency() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latency := float64(FrameMs)
+	if p.captureResampleIn != nil {
+		latency += p.captureResampleIn.latencyMs(SampleRateHz)
+	}
+	if p.captureResampleOut != nil {
+		latency += p.captureResampleOut.latencyMs(p.config.CaptureFormat.SampleRateHz)
+	}
+	return latency
+}
+
+func validateStreamFormat(format StreamFormat, channels int) error {
+	if format.SampleRateHz == 0 {
+		return nil
+	}
+	if format.SampleRateHz < 0 {
+		return fmt.Errorf("invalid sample rate %d", format.SampleRateHz)
+	}
+	if format.NumChannels != 0 && format.NumChannels != channels {
+		return fmt.Errorf("stream format channel count %d does not match %d configured channels", format.NumChannels, channels)
+	}
+	return nil
+}
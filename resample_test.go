@@ -0,0 +1,68 @@
+package apm
+
+import "testing"
+
+func TestResamplerUpsampleLength(t *testing.T) {
+	r := newResampler(16000, 48000, 1)
+
+	in := generateSineWave(440, 0.5, 160) // 10ms @ 16kHz
+	out := r.Process(in)
+
+	// Allow for filter warm-up/ring-buffer slack around the ideal 3x ratio.
+	want := len(in) * 3
+	if out != nil && (len(out) < want-16 || len(out) > want+16) {
+		t.Errorf("len(out) = %d, want ~%d", len(out), want)
+	}
+}
+
+func TestResamplerDownsampleLength(t *testing.T) {
+	r := newResampler(48000, 16000, 1)
+
+	in := generateSineWave(440, 0.5, 480) // 10ms @ 48kHz
+	out := r.Process(in)
+
+	want := len(in) / 3
+	if out != nil && (len(out) < want-16 || len(out) > want+16) {
+		t.Errorf("len(out) = %d, want ~%d", len(out), want)
+	}
+}
+
+func TestResamplerStreamingAcrossCalls(t *testing.T) {
+	r := newResampler(44100, 48000, 1)
+
+	total := 0
+	in := generateSineWave(440, 0.5, 441) // 10ms @ 44.1kHz, fed in small pieces
+	for i := 0; i < len(in); i += 37 {
+		end := i + 37
+		if end > len(in) {
+			end = len(in)
+		}
+		total += len(r.Process(in[i:end]))
+	}
+
+	want := 480 // 10ms @ 48kHz
+	if total < want-16 || total > want+16 {
+		t.Errorf("total output = %d, want ~%d", total, want)
+	}
+}
+
+func TestNewResamplerPairNative(t *testing.T) {
+	in, out := newResamplerPair(StreamFormat{}, 1)
+	if in != nil || out != nil {
+		t.Fatal("expected no resamplers for native format")
+	}
+
+	in, out = newResamplerPair(StreamFormat{SampleRateHz: SampleRateHz}, 1)
+	if in != nil || out != nil {
+		t.Fatal("expected no resamplers when SampleRateHz matches native rate")
+	}
+}
+
+func TestValidateStreamFormat(t *testing.T) {
+	if err := validateStreamFormat(StreamFormat{SampleRateHz: 44100, NumChannels: 2}, 1); err == nil {
+		t.Fatal("expected channel mismatch error")
+	}
+	if err := validateStreamFormat(StreamFormat{SampleRateHz: 44100, NumChannels: 1}, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package apm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// SampleFormat identifies the PCM sample encoding a CaptureStream/
+// RenderStream converts to/from float32 at its io.Reader/io.Writer
+// boundary. Samples are always little-endian and interleaved; for planar
+// buffers use WritePlanar on RenderStream or ReadPlanar on CaptureStream.
+type SampleFormat int
+
+const (
+	FormatFloat32 SampleFormat = iota
+	FormatInt16
+	FormatInt32
+)
+
+func (f SampleFormat) bytesPerSample() int {
+	switch f {
+	case FormatInt16:
+		return 2
+	case FormatInt32, FormatFloat32:
+		return 4
+	default:
+		return 4
+	}
+}
+
+func decodePCM(format SampleFormat, b []byte) ([]float32, error) {
+	bps := format.bytesPerSample()
+	if len(b)%bps != 0 {
+		return nil, fmt.Errorf("buffer length %d is not a multiple of %d bytes/sample", len(b), bps)
+	}
+
+	n := len(b) / bps
+	out := make([]float32, n)
+	for i := 0; i < n; i++ {
+		switch format {
+		case FormatFloat32:
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+		case FormatInt16:
+			out[i] = float32(int16(binary.LittleEndian.Uint16(b[i*2:]))) / 32768.0
+		case FormatInt32:
+			out[i] = float32(int32(binary.LittleEndian.Uint32(b[i*4:]))) / 2147483648.0
+		}
+	}
+	return out, nil
+}
+
+func encodePCM(format SampleFormat, samples []float32) []byte {
+	bps := format.bytesPerSample()
+	out := make([]byte, len(samples)*bps)
+	for i, s := range samples {
+		switch format {
+		case FormatFloat32:
+			binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(s))
+		case FormatInt16:
+			if s > 1 {
+				s = 1
+			} else if s < -1 {
+				s = -1
+			}
+			binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(s*32767.0)))
+		case FormatInt32:
+			if s > 1 {
+				s = 1
+			} else if s < -1 {
+				s = -1
+			}
+			binary.LittleEndian.PutUint32(out[i*4:], uint32(int32(s*2147483647.0)))
+		}
+	}
+	return out
+}
+
+// CaptureStream adapts a Processor's capture path to io.Reader/io.Writer:
+// callers Write raw PCM in the stream's SampleFormat (any chunk size, at the
+// Processor's configured CaptureFormat rate) and Read back cleaned PCM in
+// the same format, with 10 ms framing and APM handled internally.
+type CaptureStream struct {
+	processor *Processor
+	format    SampleFormat
+	pending   []byte // encoded output waiting to be Read
+	partial   []byte // undecoded PCM bytes left over from the last Write (not yet a whole sample)
+}
+
+// NewCaptureStream wraps processor for streaming PCM I/O in format.
+func NewCaptureStream(processor *Processor, format SampleFormat) *CaptureStream {
+	return &CaptureStream{processor: processor, format: format}
+}
+
+// Write decodes p as PCM in the stream's format and runs it through the
+// processor. Any cleaned output becomes available from Read. p may end
+// mid-sample (any chunk size is accepted); the undecodable tail is buffered
+// and decoded once a later Write completes the sample.
+func (s *CaptureStream) Write(p []byte) (n int, err error) {
+	s.partial = append(s.partial, p...)
+
+	bps := s.format.bytesPerSample()
+	usable := len(s.partial) - len(s.partial)%bps
+	if usable == 0 {
+		return len(p), nil
+	}
+
+	samples, err := decodePCM(s.format, s.partial[:usable])
+	s.partial = s.partial[usable:]
+	if err != nil {
+		return 0, err
+	}
+
+	output, err := s.processor.ProcessCaptureStream(samples)
+	if err != nil {
+		return 0, err
+	}
+
+	s.pending = append(s.pending, encodePCM(s.format, output)...)
+	return len(p), nil
+}
+
+// Read drains buffered cleaned PCM produced by prior Writes, in the
+// stream's format. It returns 0, nil if nothing is ready yet rather than
+// blocking, since more input may still be forthcoming.
+func (s *CaptureStream) Read(p []byte) (n int, err error) {
+	if len(s.pending) == 0 {
+		return 0, nil
+	}
+	n = copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// ReadFrom reads PCM from r in 4KB chunks and writes it through the
+// processor until r returns io.EOF.
+func (s *CaptureStream) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 4096)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := s.Write(buf[:n]); werr != nil {
+				return total, werr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo drains all currently buffered cleaned PCM to w.
+func (s *CaptureStream) WriteTo(w io.Writer) (int64, error) {
+	if len(s.pending) == 0 {
+		return 0, nil
+	}
+	n, err := w.Write(s.pending)
+	s.pending = s.pending[n:]
+	return int64(n), err
+}
+
+// ReadPlanar decodes and drains all buffered cleaned output for a
+// non-interleaved caller, returning one []float32 per channel.
+func (s *CaptureStream) ReadPlanar() [][]float32 {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	samples, _ := decodePCM(s.format, s.pending)
+	s.pending = nil
+	return deinterleaveFloat32(samples, s.processor.numChannels)
+}
+
+func deinterleaveFloat32(samples []float32, channels int) [][]float32 {
+	if channels == 0 {
+		return nil
+	}
+	out := make([][]float32, channels)
+	frames := len(samples) / channels
+	for ch := 0; ch < channels; ch++ {
+		out[ch] = make([]float32, frames)
+		for i := 0; i < frames; i++ {
+			out[ch][i] = samples[i*channels+ch]
+		}
+	}
+	return out
+}
+
+func interleaveFloat32(channels [][]float32) []float32 {
+	if len(channels) == 0 {
+		return nil
+	}
+	frames := len(channels[0])
+	for _, buf := range channels[1:] {
+		if len(buf) < frames {
+			frames = len(buf)
+		}
+	}
+	out := make([]float32, frames*len(channels))
+	for ch, buf := range channels {
+		for i := 0; i < frames; i++ {
+			out[i*len(channels)+ch] = buf[i]
+		}
+	}
+	return out
+}
+
+// RenderStream adapts a Processor's render path to io.Writer: callers Write
+// raw PCM in the stream's SampleFormat (any chunk size, at the Processor's
+// configured RenderFormat rate) and it is framed and fed through
+// ProcessRenderStream internally. There is no corresponding Read/output:
+// render frames produce no processed audio of their own.
+type RenderStream struct {
+	processor *Processor
+	format    SampleFormat
+	partial   []byte // undecoded PCM bytes left over from the last Write (not yet a whole sample)
+}
+
+// NewRenderStream wraps processor for streaming render PCM input in format.
+func NewRenderStream(processor *Processor, format SampleFormat) *RenderStream {
+	return &RenderStream{processor: processor, format: format}
+}
+
+// Write decodes p as PCM in the stream's format and feeds it through the
+// processor's render path. p may end mid-sample (any chunk size is
+// accepted); the undecodable tail is buffered and decoded once a later
+// Write completes the sample.
+func (s *RenderStream) Write(p []byte) (n int, err error) {
+	s.partial = append(s.partial, p...)
+
+	bps := s.format.bytesPerSample()
+	usable := len(s.partial) - len(s.partial)%bps
+	if usable == 0 {
+		return len(p), nil
+	}
+
+	samples, err := decodePCM(s.format, s.partial[:usable])
+	s.partial = s.partial[usable:]
+	if err != nil {
+		return 0, err
+	}
+	if err := s.processor.ProcessRenderStream(samples); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReadFrom reads PCM from r in 4KB chunks and feeds it through the
+// processor's render path until r returns io.EOF.
+func (s *RenderStream) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 4096)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := s.Write(buf[:n]); werr != nil {
+				return total, werr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WritePlanar feeds non-interleaved render audio (one []float32 per
+// channel) through the processor's render path.
+func (s *RenderStream) WritePlanar(channels [][]float32) error {
+	return s.processor.ProcessRenderStream(interleaveFloat32(channels))
+}
@@ -0,0 +1,166 @@
+package apm
+
+import "testing"
+
+func TestPCMRoundtripInt16(t *testing.T) {
+	samples := generateSineWave(440, 0.5, 64)
+
+	encoded := encodePCM(FormatInt16, samples)
+	decoded, err := decodePCM(FormatInt16, encoded)
+	if err != nil {
+		t.Fatalf("decodePCM failed: %v", err)
+	}
+
+	for i, s := range samples {
+		if diff := s - decoded[i]; diff > 1e-3 || diff < -1e-3 {
+			t.Fatalf("sample %d: got %f, want ~%f", i, decoded[i], s)
+		}
+	}
+}
+
+func TestPCMRoundtripFloat32(t *testing.T) {
+	samples := generateSineWave(1000, 0.3, 32)
+
+	encoded := encodePCM(FormatFloat32, samples)
+	decoded, err := decodePCM(FormatFloat32, encoded)
+	if err != nil {
+		t.Fatalf("decodePCM failed: %v", err)
+	}
+
+	for i, s := range samples {
+		if decoded[i] != s {
+			t.Fatalf("sample %d: got %f, want %f", i, decoded[i], s)
+		}
+	}
+}
+
+func TestDecodePCMInvalidLength(t *testing.T) {
+	if _, err := decodePCM(FormatInt16, make([]byte, 3)); err == nil {
+		t.Fatal("expected error for buffer not a multiple of bytes/sample")
+	}
+}
+
+func TestInterleaveDeinterleaveRoundtrip(t *testing.T) {
+	left := generateSineWave(440, 0.5, 16)
+	right := generateSineWave(880, 0.3, 16)
+
+	interleaved := interleaveFloat32([][]float32{left, right})
+	channels := deinterleaveFloat32(interleaved, 2)
+
+	for i := range left {
+		if channels[0][i] != left[i] || channels[1][i] != right[i] {
+			t.Fatalf("frame %d did not round-trip", i)
+		}
+	}
+}
+
+func TestInterleaveTruncatesToShortestChannel(t *testing.T) {
+	long := generateSineWave(440, 0.5, 16)
+	short := generateSineWave(880, 0.3, 10)
+
+	interleaved := interleaveFloat32([][]float32{long, short})
+	if got, want := len(interleaved), len(short)*2; got != want {
+		t.Fatalf("len(interleaved) = %d, want %d (truncated to shortest channel)", got, want)
+	}
+
+	channels := deinterleaveFloat32(interleaved, 2)
+	for i := range short {
+		if channels[0][i] != long[i] || channels[1][i] != short[i] {
+			t.Fatalf("frame %d did not match source channels", i)
+		}
+	}
+}
+
+func TestCaptureStreamWriteRead(t *testing.T) {
+	processor, err := New(Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer processor.Close()
+
+	stream := NewCaptureStream(processor, FormatInt16)
+
+	samples := generateSineWave(440, 0.5, NumSamplesPerFrame)
+	pcm := encodePCM(FormatInt16, samples)
+
+	if _, err := stream.Write(pcm); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	out := make([]byte, len(pcm)*2)
+	n, err := stream.Read(out)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if n != len(pcm) {
+		t.Errorf("Read returned %d bytes, want %d", n, len(pcm))
+	}
+}
+
+func TestCaptureStreamWriteUnalignedChunks(t *testing.T) {
+	processor, err := New(Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer processor.Close()
+
+	stream := NewCaptureStream(processor, FormatInt16)
+
+	samples := generateSineWave(440, 0.5, NumSamplesPerFrame)
+	pcm := encodePCM(FormatInt16, samples)
+
+	// Split the PCM into odd-sized chunks that don't land on 2-byte sample
+	// boundaries, to exercise the tail-byte buffering in Write.
+	const chunkSize = 3
+	for i := 0; i < len(pcm); i += chunkSize {
+		end := i + chunkSize
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+		if _, err := stream.Write(pcm[i:end]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	out := make([]byte, len(pcm)*2)
+	n, err := stream.Read(out)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if n != len(pcm) {
+		t.Errorf("Read returned %d bytes, want %d", n, len(pcm))
+	}
+}
+
+func TestRenderStreamWriteUnalignedChunks(t *testing.T) {
+	processor, err := New(Config{
+		CaptureChannels: 1,
+		RenderChannels:  1,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer processor.Close()
+
+	stream := NewRenderStream(processor, FormatInt16)
+
+	samples := generateSineWave(1000, 0.4, NumSamplesPerFrame)
+	pcm := encodePCM(FormatInt16, samples)
+
+	const chunkSize = 3
+	for i := 0; i < len(pcm); i += chunkSize {
+		end := i + chunkSize
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+		if _, err := stream.Write(pcm[i:end]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+}